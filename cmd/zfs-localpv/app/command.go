@@ -0,0 +1,35 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package app assembles the `zfs-localpv` root command and its
+// subcommands.
+package app
+
+import (
+	"github.com/openebs/zfs-localpv/pkg/lint"
+	"github.com/spf13/cobra"
+)
+
+// NewZFSLocalPVCommand returns the `zfs-localpv` root command with every
+// subcommand registered.
+func NewZFSLocalPVCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "zfs-localpv",
+		Short: "zfs-localpv is the ZFS LocalPV CSI driver and its operator tooling",
+	}
+
+	cmd.AddCommand(lint.NewCmdLint())
+
+	return cmd
+}
@@ -0,0 +1,218 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package v1 holds the current openebs.io/zfs CRD types: ZFSVolume,
+// ZFSSnapshot, ZFSBackup and ZFSRestore. See pkg/zfs/resource for how
+// callers fall back to openebs.io/zfs/v1alpha1 while a cluster's CRD
+// storage version is mid upgrade.
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VolumeInfo is the spec of a ZFSVolume CR: the ZFS dataset a ZFSVolume CR
+// is bound to.
+type VolumeInfo struct {
+	// OwnerNodeID is the node the dataset is created on. The CSI node
+	// plugin on that node is the only one that may act on this volume.
+	OwnerNodeID string `json:"ownerNodeID,omitempty"`
+	// PoolName is the ZFS pool the dataset lives in.
+	PoolName string `json:"poolName,omitempty"`
+	// Capacity is the size of the dataset, in bytes, as a decimal string.
+	Capacity string `json:"capacity,omitempty"`
+	// FsType is the filesystem formatted onto the volume, e.g. "zfs" or
+	// "ext4" for a zvol.
+	FsType string `json:"fsType,omitempty"`
+	// VolumeType distinguishes a ZFS filesystem dataset from a zvol.
+	VolumeType string `json:"volumeType,omitempty"`
+}
+
+// VolStatus is the status of a ZFSVolume CR.
+type VolStatus struct {
+	// State is one of ZFSStatusPending, ZFSStatusReady or ZFSStatusFailed.
+	State string `json:"state,omitempty"`
+}
+
+// ZFSVolume represents a ZFS dataset provisioned by the CSI controller and
+// bound to the node that owns it.
+type ZFSVolume struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VolumeInfo `json:"spec,omitempty"`
+	Status VolStatus  `json:"status,omitempty"`
+}
+
+// ZFSVolumeList is a list of ZFSVolume resources.
+type ZFSVolumeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ZFSVolume `json:"items"`
+}
+
+// SnapshotInfo is the spec of a ZFSSnapshot CR.
+type SnapshotInfo struct {
+	// OwnerNodeID is the node the source dataset lives on.
+	OwnerNodeID string `json:"ownerNodeID,omitempty"`
+	// PoolName is the ZFS pool the source dataset lives in.
+	PoolName string `json:"poolName,omitempty"`
+	// VolumeName is the ZFSVolume this snapshot was taken of.
+	VolumeName string `json:"volumeName,omitempty"`
+}
+
+// SnapStatus is the status of a ZFSSnapshot CR.
+type SnapStatus struct {
+	// State is one of ZFSStatusPending, ZFSStatusReady or ZFSStatusFailed.
+	State string `json:"state,omitempty"`
+}
+
+// ZFSSnapshot represents a `zfs snapshot` taken of a ZFSVolume's dataset.
+type ZFSSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SnapshotInfo `json:"spec,omitempty"`
+	Status SnapStatus   `json:"status,omitempty"`
+}
+
+// ZFSSnapshotList is a list of ZFSSnapshot resources.
+type ZFSSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ZFSSnapshot `json:"items"`
+}
+
+// ZFSBackupSpec describes where a ZFSBackup's data should land.
+// BackendType selects the SnapshotBackend (see pkg/zfs.SnapshotBackend);
+// an empty value resolves to the native `zfs send`/`zfs recv` path, in
+// which case CredentialSecretName, BackupDest and Zone are unused.
+type ZFSBackupSpec struct {
+	// VolumeName is the ZFSVolume being backed up.
+	VolumeName string `json:"volumeName,omitempty"`
+	// SnapName is the ZFS snapshot this backup sends.
+	SnapName string `json:"snapName,omitempty"`
+	// PrevSnapName is the parent snapshot this backup sends incrementally
+	// against (`zfs send -i`). Empty means a full send.
+	PrevSnapName string `json:"prevSnapName,omitempty"`
+	// BackendType selects the SnapshotBackend that performs this backup.
+	BackendType string `json:"backendType,omitempty"`
+	// CredentialSecretName names the Secret, in OpenEBSNamespace, holding
+	// the backend's cloud credentials.
+	CredentialSecretName string `json:"credentialSecretName,omitempty"`
+	// BackupDest is the backend-specific destination: a GCE project ID for
+	// BackendTypeGCEPD, an AWS region for BackendTypeAWSEBS.
+	BackupDest string `json:"backupDest,omitempty"`
+	// Zone is the GCE zone to create the staging disk/snapshot in. Only
+	// read by BackendTypeGCEPD, since GCE disks and snapshots are zonal
+	// resources.
+	Zone string `json:"zone,omitempty"`
+	// GCSStagingBucket is the GCS bucket BackendTypeGCEPD stages the
+	// zvol's raw bytes through on their way to a GCE image/disk/snapshot.
+	// Unused by BackendTypeAWSEBS, which attaches a staging EBS volume to
+	// the local instance directly instead.
+	GCSStagingBucket string `json:"gcsStagingBucket,omitempty"`
+}
+
+// ZFSBackupStatus records the result of a backup, including backend
+// specific identifiers needed to restore from it later.
+type ZFSBackupStatus struct {
+	// SnapName is the ZFS snapshot that was sent.
+	SnapName string `json:"snapName,omitempty"`
+	// SentBytes is the number of bytes the send transferred.
+	SentBytes int64 `json:"sentBytes,omitempty"`
+	// SendDuration is how long the send took, formatted via
+	// time.Duration.String().
+	SendDuration string `json:"sendDuration,omitempty"`
+	// EBSSnapshotID is the AWS EBS snapshot ID, set by BackendTypeAWSEBS.
+	EBSSnapshotID string `json:"ebsSnapshotID,omitempty"`
+	// GCESnapshotSelfLink is the GCE snapshot self-link, set by
+	// BackendTypeGCEPD.
+	GCESnapshotSelfLink string `json:"gceSnapshotSelfLink,omitempty"`
+}
+
+// ZFSBackup represents a single backup of a ZFSVolume's dataset, either to
+// another ZFS pool via send/recv or to a cloud snapshot backend.
+type ZFSBackup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ZFSBackupSpec   `json:"spec,omitempty"`
+	Status ZFSBackupStatus `json:"status,omitempty"`
+}
+
+// ZFSBackupList is a list of ZFSBackup resources.
+type ZFSBackupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ZFSBackup `json:"items"`
+}
+
+// ZFSRestoreSpec describes where a ZFSRestore should read its data from.
+// Mode selects ZFSRestoreModeEncrypted for the deferred-binding flow (see
+// pkg/zfs.IsEncryptedRestore); BackendType/CredentialSecretName/RestoreSrc
+// mirror ZFSBackupSpec's backend fields and EBSSnapshotID/
+// GCESnapshotSelfLink name the specific cloud snapshot a prior ZFSBackup
+// produced.
+type ZFSRestoreSpec struct {
+	// VolumeName is the ZFSVolume being restored into.
+	VolumeName string `json:"volumeName,omitempty"`
+	// Mode is ZFSRestoreModeEncrypted for an encrypted-pool restore, empty
+	// otherwise.
+	Mode string `json:"mode,omitempty"`
+	// BackendType selects the SnapshotBackend that performs this restore.
+	BackendType string `json:"backendType,omitempty"`
+	// CredentialSecretName names the Secret, in OpenEBSNamespace, holding
+	// the backend's cloud credentials.
+	CredentialSecretName string `json:"credentialSecretName,omitempty"`
+	// RestoreSrc is the backend-specific source: a GCE project ID for
+	// BackendTypeGCEPD, an AWS region for BackendTypeAWSEBS.
+	RestoreSrc string `json:"restoreSrc,omitempty"`
+	// Zone is the GCE zone to create the restored disk in. Only read by
+	// BackendTypeGCEPD.
+	Zone string `json:"zone,omitempty"`
+	// EBSSnapshotID is the AWS EBS snapshot ID to restore from, set by
+	// whoever created this ZFSRestore from a ZFSBackup's status.
+	EBSSnapshotID string `json:"ebsSnapshotID,omitempty"`
+	// GCESnapshotSelfLink is the GCE snapshot self-link to restore from.
+	GCESnapshotSelfLink string `json:"gceSnapshotSelfLink,omitempty"`
+}
+
+// ZFSRestoreStatus records the progress of a restore.
+type ZFSRestoreStatus struct {
+	// State is one of ZFSStatusPending, ZFSRestoreStatusDatasetReady,
+	// ZFSStatusReady or ZFSStatusFailed.
+	State string `json:"state,omitempty"`
+}
+
+// ZFSRestore represents a single restore of a ZFSVolume's dataset, either
+// from another ZFS pool via send/recv or from a cloud snapshot backend.
+type ZFSRestore struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ZFSRestoreSpec   `json:"spec,omitempty"`
+	Status ZFSRestoreStatus `json:"status,omitempty"`
+}
+
+// ZFSRestoreList is a list of ZFSRestore resources.
+type ZFSRestoreList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ZFSRestore `json:"items"`
+}
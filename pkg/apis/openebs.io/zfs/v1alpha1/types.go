@@ -0,0 +1,85 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package v1alpha1 holds the legacy openebs.io/zfs CRD types that
+// pkg/zfs/resource falls back to and converts up to v1 while a cluster's
+// CRD storage version is mid upgrade. Only ZFSVolume and ZFSSnapshot have
+// a registered conversion today -- ZFSBackup/ZFSRestore were introduced
+// after the v1alpha1 API was retired, so they never shipped a legacy
+// version.
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VolumeInfo is the spec of a legacy ZFSVolume CR.
+type VolumeInfo struct {
+	OwnerNodeID string `json:"ownerNodeID,omitempty"`
+	PoolName    string `json:"poolName,omitempty"`
+	Capacity    string `json:"capacity,omitempty"`
+	FsType      string `json:"fsType,omitempty"`
+	VolumeType  string `json:"volumeType,omitempty"`
+}
+
+// VolStatus is the status of a legacy ZFSVolume CR.
+type VolStatus struct {
+	State string `json:"state,omitempty"`
+}
+
+// ZFSVolume is the legacy openebs.io/zfs/v1alpha1 ZFSVolume CR.
+type ZFSVolume struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VolumeInfo `json:"spec,omitempty"`
+	Status VolStatus  `json:"status,omitempty"`
+}
+
+// ZFSVolumeList is a list of legacy ZFSVolume resources.
+type ZFSVolumeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ZFSVolume `json:"items"`
+}
+
+// SnapshotInfo is the spec of a legacy ZFSSnapshot CR.
+type SnapshotInfo struct {
+	OwnerNodeID string `json:"ownerNodeID,omitempty"`
+	PoolName    string `json:"poolName,omitempty"`
+	VolumeName  string `json:"volumeName,omitempty"`
+}
+
+// SnapStatus is the status of a legacy ZFSSnapshot CR.
+type SnapStatus struct {
+	State string `json:"state,omitempty"`
+}
+
+// ZFSSnapshot is the legacy openebs.io/zfs/v1alpha1 ZFSSnapshot CR.
+type ZFSSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SnapshotInfo `json:"spec,omitempty"`
+	Status SnapStatus   `json:"status,omitempty"`
+}
+
+// ZFSSnapshotList is a list of legacy ZFSSnapshot resources.
+type ZFSSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []ZFSSnapshot `json:"items"`
+}
@@ -0,0 +1,302 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/openebs/zfs-localpv/pkg/zfs"
+	storagev1 "k8s.io/api/storage/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// restorePendingThreshold is how long a ZFSRestore may sit in Pending
+// before stuckRestoreCheck flags it.
+const restorePendingThreshold = 15 * time.Minute
+
+func init() {
+	RegisterCheck(&orphanSnapshotCheck{})
+	RegisterCheck(&invalidBackupSnapshotCheck{})
+	RegisterCheck(&stuckRestoreCheck{})
+	RegisterCheck(&orphanNodeVolumeCheck{})
+	RegisterCheck(&orphanFinalizerCheck{})
+	RegisterCheck(&duplicatePoolLabelCheck{})
+}
+
+// orphanSnapshotCheck flags ZFSSnapshot CRs whose source ZFSVolume has
+// already been deleted.
+type orphanSnapshotCheck struct{}
+
+func (c *orphanSnapshotCheck) Name() string {
+	return "orphan-snapshot"
+}
+
+func (c *orphanSnapshotCheck) Run() ([]Issue, error) {
+	snapList, err := zfs.ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	for _, snap := range snapList.Items {
+		volName := snap.Labels[zfs.ZFSVolKey]
+		if volName == "" {
+			continue
+		}
+
+		if _, err := zfs.GetVolume(volName); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return nil, err
+			}
+			issues = append(issues, Issue{
+				Check:    c.Name(),
+				Severity: SeverityWarning,
+				Resource: fmt.Sprintf("ZFSSnapshot/%s", snap.Name),
+				Message:  fmt.Sprintf("points at ZFSVolume %q which no longer exists", volName),
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// invalidBackupSnapshotCheck flags ZFSBackup CRs whose Spec.SnapName no
+// longer has a matching ZFSSnapshot CR, which means the on-node snapshot
+// behind it is gone too.
+type invalidBackupSnapshotCheck struct{}
+
+func (c *invalidBackupSnapshotCheck) Name() string {
+	return "invalid-backup-snapshot"
+}
+
+func (c *invalidBackupSnapshotCheck) Run() ([]Issue, error) {
+	bkpList, err := zfs.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	for _, bkp := range bkpList.Items {
+		if _, err := zfs.GetZFSSnapshot(bkp.Spec.SnapName); err != nil {
+			if !k8serrors.IsNotFound(err) {
+				return nil, err
+			}
+			issues = append(issues, Issue{
+				Check:    c.Name(),
+				Severity: SeverityError,
+				Resource: fmt.Sprintf("ZFSBackup/%s", bkp.Name),
+				Message:  fmt.Sprintf("Spec.SnapName %q no longer exists", bkp.Spec.SnapName),
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// stuckRestoreCheck flags ZFSRestore CRs that have sat in Pending for at
+// least restorePendingThreshold.
+type stuckRestoreCheck struct{}
+
+func (c *stuckRestoreCheck) Name() string {
+	return "stuck-restore"
+}
+
+func (c *stuckRestoreCheck) Run() ([]Issue, error) {
+	rstrList, err := zfs.ListRestores()
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	for _, rstr := range rstrList.Items {
+		age := time.Since(rstr.CreationTimestamp.Time)
+		if !restoreIsStuck(rstr.Status.State, age) {
+			continue
+		}
+
+		issues = append(issues, Issue{
+			Check:    c.Name(),
+			Severity: SeverityError,
+			Resource: fmt.Sprintf("ZFSRestore/%s", rstr.Name),
+			Message:  fmt.Sprintf("stuck in Pending for %s (threshold %s)", age.Round(time.Second), restorePendingThreshold),
+		})
+	}
+
+	return issues, nil
+}
+
+// restoreIsStuck reports whether a ZFSRestore with the given Status.State
+// and age has sat in Pending for at least restorePendingThreshold.
+func restoreIsStuck(state string, age time.Duration) bool {
+	return state == zfs.ZFSStatusPending && age >= restorePendingThreshold
+}
+
+// orphanNodeVolumeCheck flags ZFSVolume CRs whose Spec.OwnerNodeID no
+// longer refers to a node in the cluster, e.g. after a node was deleted
+// without draining its volumes first.
+type orphanNodeVolumeCheck struct{}
+
+func (c *orphanNodeVolumeCheck) Name() string {
+	return "orphan-node-volume"
+}
+
+func (c *orphanNodeVolumeCheck) Run() ([]Issue, error) {
+	volList, err := zfs.ListVolumes()
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubeClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	nodeList, err := clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string]bool, len(nodeList.Items))
+	for _, node := range nodeList.Items {
+		nodes[node.Name] = true
+	}
+
+	var issues []Issue
+	for _, vol := range volList.Items {
+		if vol.Spec.OwnerNodeID == "" || nodes[vol.Spec.OwnerNodeID] {
+			continue
+		}
+
+		issues = append(issues, Issue{
+			Check:    c.Name(),
+			Severity: SeverityError,
+			Resource: fmt.Sprintf("ZFSVolume/%s", vol.Name),
+			Message:  fmt.Sprintf("OwnerNodeID %q is not a node in the cluster", vol.Spec.OwnerNodeID),
+		})
+	}
+
+	return issues, nil
+}
+
+// orphanFinalizerCheck flags ZFSVolume CRs that still carry
+// zfs.ZFSFinalizer even though the dataset behind them is already gone on
+// disk. It only inspects volumes owned by this node -- the finalizer's
+// dataset can only be checked by the node agent that would have created
+// it.
+type orphanFinalizerCheck struct{}
+
+func (c *orphanFinalizerCheck) Name() string {
+	return "orphan-finalizer"
+}
+
+func (c *orphanFinalizerCheck) Run() ([]Issue, error) {
+	volList, err := zfs.ListVolumes()
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []Issue
+	for _, vol := range volList.Items {
+		if len(vol.Finalizers) == 0 {
+			continue
+		}
+		if vol.Labels[zfs.ZFSNodeKey] != zfs.NodeID {
+			continue
+		}
+
+		dataset := vol.Spec.PoolName + "/" + vol.Name
+		if err := exec.Command("zfs", "list", dataset).Run(); err != nil {
+			issues = append(issues, Issue{
+				Check:    c.Name(),
+				Severity: SeverityWarning,
+				Resource: fmt.Sprintf("ZFSVolume/%s", vol.Name),
+				Message:  fmt.Sprintf("has finalizer %v but dataset %q is gone", vol.Finalizers, dataset),
+			})
+		}
+	}
+
+	return issues, nil
+}
+
+// duplicatePoolLabelCheck flags sets of StorageClasses that target the
+// same zfs.PoolNameKey pool with different parameters, which means
+// volumes provisioned through them would collide on pool settings like
+// fstype or compression.
+type duplicatePoolLabelCheck struct{}
+
+func (c *duplicatePoolLabelCheck) Name() string {
+	return "duplicate-pool-label"
+}
+
+func (c *duplicatePoolLabelCheck) Run() ([]Issue, error) {
+	clientset, err := kubeClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	scList, err := clientset.StorageV1().StorageClasses().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	byPool := map[string][]storagev1.StorageClass{}
+	for _, sc := range scList.Items {
+		pool, ok := sc.Parameters[zfs.PoolNameKey]
+		if !ok {
+			continue
+		}
+		byPool[pool] = append(byPool[pool], sc)
+	}
+
+	var issues []Issue
+	for pool, scs := range byPool {
+		if len(scs) < 2 || !parametersConflict(scs) {
+			continue
+		}
+
+		names := make([]string, 0, len(scs))
+		for _, sc := range scs {
+			names = append(names, sc.Name)
+		}
+		sort.Strings(names)
+
+		issues = append(issues, Issue{
+			Check:    c.Name(),
+			Severity: SeverityWarning,
+			Resource: fmt.Sprintf("pool/%s", pool),
+			Message:  fmt.Sprintf("StorageClasses %v share pool %q with conflicting parameters", names, pool),
+		})
+	}
+
+	return issues, nil
+}
+
+// parametersConflict reports whether any StorageClass in scs has a
+// different Parameters map than the first one.
+func parametersConflict(scs []storagev1.StorageClass) bool {
+	first := scs[0].Parameters
+	for _, sc := range scs[1:] {
+		if !reflect.DeepEqual(first, sc.Parameters) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"testing"
+	"time"
+
+	"github.com/openebs/zfs-localpv/pkg/zfs"
+	storagev1 "k8s.io/api/storage/v1"
+)
+
+func TestRestoreIsStuck(t *testing.T) {
+	tests := []struct {
+		name  string
+		state string
+		age   time.Duration
+		want  bool
+	}{
+		{"pending and younger than threshold", zfs.ZFSStatusPending, restorePendingThreshold - time.Minute, false},
+		{"pending and exactly at threshold", zfs.ZFSStatusPending, restorePendingThreshold, true},
+		{"pending and older than threshold", zfs.ZFSStatusPending, restorePendingThreshold + time.Minute, true},
+		{"ready and older than threshold", zfs.ZFSStatusReady, restorePendingThreshold + time.Minute, false},
+		{"failed and older than threshold", zfs.ZFSStatusFailed, restorePendingThreshold + time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := restoreIsStuck(tt.state, tt.age); got != tt.want {
+				t.Errorf("restoreIsStuck(%q, %s) = %v, want %v", tt.state, tt.age, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParametersConflict(t *testing.T) {
+	tests := []struct {
+		name string
+		scs  []storagev1.StorageClass
+		want bool
+	}{
+		{
+			name: "single StorageClass never conflicts",
+			scs: []storagev1.StorageClass{
+				{Parameters: map[string]string{"fstype": "zfs"}},
+			},
+			want: false,
+		},
+		{
+			name: "identical parameters do not conflict",
+			scs: []storagev1.StorageClass{
+				{Parameters: map[string]string{"fstype": "zfs", "compression": "on"}},
+				{Parameters: map[string]string{"fstype": "zfs", "compression": "on"}},
+			},
+			want: false,
+		},
+		{
+			name: "differing values conflict",
+			scs: []storagev1.StorageClass{
+				{Parameters: map[string]string{"fstype": "zfs"}},
+				{Parameters: map[string]string{"fstype": "ext4"}},
+			},
+			want: true,
+		},
+		{
+			name: "differing key sets conflict",
+			scs: []storagev1.StorageClass{
+				{Parameters: map[string]string{"fstype": "zfs"}},
+				{Parameters: map[string]string{"fstype": "zfs", "compression": "on"}},
+			},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parametersConflict(tt.scs); got != tt.want {
+				t.Errorf("parametersConflict() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// NewCmdLint returns the `zfs-localpv lint` subcommand. It is meant to be
+// added to the root command's command tree next to the other
+// `zfs-localpv` subcommands.
+func NewCmdLint() *cobra.Command {
+	var jsonOutput bool
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "Validate ZFSVolume/ZFSSnapshot/ZFSBackup/ZFSRestore CRs for common problems",
+		Long: `lint walks every ZFSVolume, ZFSSnapshot, ZFSBackup, and ZFSRestore CR in
+the OpenEBS namespace and reports problems such as orphan snapshots, backups
+whose snapshot is gone, and stuck restores. It exits 0 when clean, 1 when
+only warnings or info were found, and 2 when at least one error was found,
+so it can gate a CronJob or a CI pipeline.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runLint(jsonOutput)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "emit issues as a JSON array instead of plain text")
+
+	return cmd
+}
+
+func runLint(jsonOutput bool) error {
+	issues := Run()
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			return err
+		}
+	} else {
+		if len(issues) == 0 {
+			fmt.Println("lint: no issues found")
+		}
+		for _, issue := range issues {
+			fmt.Println(issue.String())
+		}
+	}
+
+	os.Exit(ExitCode(issues))
+	return nil
+}
@@ -0,0 +1,43 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"sync"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+var (
+	clientsetOnce sync.Once
+	clientset     kubernetes.Interface
+	clientsetErr  error
+)
+
+// kubeClientset lazily builds the in-cluster kubernetes.Interface used by
+// checks that need cluster-level objects (Nodes, StorageClasses) the
+// pkg/zfs getters don't expose.
+func kubeClientset() (kubernetes.Interface, error) {
+	clientsetOnce.Do(func() {
+		cfg, err := rest.InClusterConfig()
+		if err != nil {
+			clientsetErr = err
+			return
+		}
+		clientset, clientsetErr = kubernetes.NewForConfig(cfg)
+	})
+	return clientset, clientsetErr
+}
@@ -0,0 +1,135 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lint walks the ZFSVolume/ZFSSnapshot/ZFSBackup/ZFSRestore CRs in
+// OpenEBSNamespace and reports problems that are easy to miss by eyeballing
+// `kubectl get`, such as snapshots pointing at deleted volumes or restores
+// stuck in Pending. It is meant to run as a CronJob or a CI gate via the
+// `zfs-localpv lint` subcommand.
+package lint
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/openebs/zfs-localpv/pkg/zfs"
+)
+
+// Severity classifies how urgently an Issue needs attention.
+type Severity string
+
+const (
+	// SeverityInfo is a note that does not need action, e.g. a resource
+	// nearing a soft threshold.
+	SeverityInfo Severity = "info"
+	// SeverityWarning is a problem worth investigating but not yet
+	// affecting the cluster.
+	SeverityWarning Severity = "warning"
+	// SeverityError is a problem that is already broken or about to break
+	// provisioning/backup/restore.
+	SeverityError Severity = "error"
+)
+
+// Issue is a single violation surfaced by a Check.
+type Issue struct {
+	// Check is the name of the Check that produced this Issue.
+	Check string `json:"check"`
+	// Severity classifies how urgent the Issue is.
+	Severity Severity `json:"severity"`
+	// Resource is the Kind/name of what the Issue is about, e.g.
+	// "ZFSSnapshot/pvc-123-snap", or "-" when the Check itself failed.
+	Resource string `json:"resource"`
+	// Message is a human-readable description of the violation.
+	Message string `json:"message"`
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("[%s] %s: %s", i.Severity, i.Resource, i.Message)
+}
+
+// Check is a single pluggable lint rule. Checks are registered via
+// RegisterCheck and run by Run in lexical order by Name.
+type Check interface {
+	// Name identifies the Check in Issue.Check and in the registry.
+	Name() string
+	// Run inspects cluster state and returns the Issues it finds.
+	Run() ([]Issue, error)
+}
+
+// checks holds the registered Checks, keyed by Name so a duplicate
+// registration is a programming error caught at startup rather than a
+// silent double-run.
+var checks = map[string]Check{}
+
+// RegisterCheck adds a Check to the set Run executes. Checks register
+// themselves from an init() in their own file.
+func RegisterCheck(c Check) {
+	if _, exists := checks[c.Name()]; exists {
+		panic(fmt.Sprintf("lint: check %q already registered", c.Name()))
+	}
+	checks[c.Name()] = c
+}
+
+// Run executes every registered Check against the cluster reachable via
+// zfs.OpenEBSNamespace and returns the combined Issues. It does not stop
+// at the first failing Check -- a kube API error from one check is
+// reported as its own Issue so the rest still run. Checks run in
+// lexical order by Name so `lint --json` output is stable between runs,
+// a property CI gating depends on.
+func Run() []Issue {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var issues []Issue
+	for _, name := range names {
+		found, err := checks[name].Run()
+		if err != nil {
+			issues = append(issues, Issue{
+				Check:    name,
+				Severity: SeverityError,
+				Resource: "-",
+				Message:  fmt.Sprintf("check failed to run: %s", err.Error()),
+			})
+			continue
+		}
+		issues = append(issues, found...)
+	}
+
+	return issues
+}
+
+// ExitCode returns the process exit code Run's Issues should map to, so
+// the linter can gate a CronJob or CI pipeline: 0 when clean, 1 when only
+// warnings/info were found, 2 when at least one error was found.
+func ExitCode(issues []Issue) int {
+	code := 0
+	for _, issue := range issues {
+		switch issue.Severity {
+		case SeverityError:
+			return 2
+		case SeverityWarning, SeverityInfo:
+			code = 1
+		}
+	}
+	return code
+}
+
+// namespace returns zfs.OpenEBSNamespace, the namespace whose CRs the
+// checks inspect through the pkg/zfs getters.
+func namespace() string {
+	return zfs.OpenEBSNamespace
+}
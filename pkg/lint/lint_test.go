@@ -0,0 +1,70 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lint
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fakeCheck struct {
+	name   string
+	issues []Issue
+}
+
+func (c *fakeCheck) Name() string { return c.name }
+
+func (c *fakeCheck) Run() ([]Issue, error) { return c.issues, nil }
+
+func TestRunOrdersIssuesByCheckName(t *testing.T) {
+	saved := checks
+	defer func() { checks = saved }()
+	checks = map[string]Check{}
+
+	RegisterCheck(&fakeCheck{name: "zzz-check", issues: []Issue{{Check: "zzz-check", Resource: "a"}}})
+	RegisterCheck(&fakeCheck{name: "aaa-check", issues: []Issue{{Check: "aaa-check", Resource: "b"}}})
+
+	got := Run()
+
+	want := []string{"aaa-check", "zzz-check"}
+	var gotNames []string
+	for _, issue := range got {
+		gotNames = append(gotNames, issue.Check)
+	}
+
+	if !reflect.DeepEqual(gotNames, want) {
+		t.Errorf("Run() issue order = %v, want %v", gotNames, want)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := map[string]struct {
+		issues []Issue
+		want   int
+	}{
+		"no issues":    {nil, 0},
+		"only info":    {[]Issue{{Severity: SeverityInfo}}, 1},
+		"only warning": {[]Issue{{Severity: SeverityWarning}}, 1},
+		"has error":    {[]Issue{{Severity: SeverityWarning}, {Severity: SeverityError}}, 2},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := ExitCode(tt.issues); got != tt.want {
+				t.Errorf("ExitCode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
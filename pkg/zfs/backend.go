@@ -0,0 +1,111 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zfs
+
+import (
+	"fmt"
+
+	apis "github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1"
+)
+
+const (
+	// BackendTypeZFS is the native `zfs send`/`zfs recv` backup path. This
+	// is the default when Spec.BackendType is left empty, so existing
+	// ZFSBackup/ZFSRestore CRs keep working unmodified.
+	BackendTypeZFS string = "zfs"
+	// BackendTypeAWSEBS snapshots/restores via AWS EBS snapshots.
+	BackendTypeAWSEBS string = "aws-ebs"
+	// BackendTypeGCEPD snapshots/restores via GCE persistent disk snapshots.
+	BackendTypeGCEPD string = "gce-pd"
+)
+
+// SnapshotBackend abstracts where a ZFSBackup's data ultimately lands and
+// where a ZFSRestore reads it back from. The zfs send/recv pipeline is one
+// implementation; awsEBSBackend and gcePDBackend let a backup/restore hop
+// across clouds instead of streaming straight to another ZFS pool.
+type SnapshotBackend interface {
+	// Name returns the Spec.BackendType value this backend handles.
+	Name() string
+
+	// Backup snapshots the volume behind bkp and records backend-specific
+	// identifiers (e.g. a cloud snapshot self-link) in the returned status.
+	Backup(bkp *apis.ZFSBackup) (apis.ZFSBackupStatus, error)
+
+	// Restore provisions the volume behind rstr from the snapshot recorded
+	// by a prior Backup call and returns the resulting status.
+	Restore(rstr *apis.ZFSRestore) (apis.ZFSRestoreStatus, error)
+}
+
+// backends holds the registered SnapshotBackend implementations, keyed by
+// their Spec.BackendType value.
+var backends = map[string]SnapshotBackend{}
+
+// RegisterBackend adds a SnapshotBackend to the registry used by
+// GetBackend. Backends register themselves from an init() in their own
+// file, mirroring how the builder packages register their kubeclients.
+func RegisterBackend(b SnapshotBackend) {
+	backends[b.Name()] = b
+}
+
+// GetBackend returns the SnapshotBackend for the given Spec.BackendType. An
+// empty backendType resolves to BackendTypeZFS so existing CRs created
+// before this field existed keep using the native send/recv path.
+func GetBackend(backendType string) (SnapshotBackend, error) {
+	if backendType == "" {
+		backendType = BackendTypeZFS
+	}
+
+	b, ok := backends[backendType]
+	if !ok {
+		return nil, fmt.Errorf("no snapshot backend registered for type %q", backendType)
+	}
+
+	return b, nil
+}
+
+// RunBackup dispatches bkp to the SnapshotBackend named by
+// bkp.Spec.BackendType, then persists the resulting status via
+// UpdateBkpInfo, the same way a caller driving the native send/recv path
+// already does.
+func RunBackup(bkp *apis.ZFSBackup) error {
+	backend, err := GetBackend(bkp.Spec.BackendType)
+	if err != nil {
+		return err
+	}
+
+	status, err := backend.Backup(bkp)
+	if err != nil {
+		return err
+	}
+
+	return UpdateBkpInfo(bkp, status)
+}
+
+// RunRestore dispatches rstr to the SnapshotBackend named by
+// rstr.Spec.BackendType, then persists the resulting status via
+// UpdateRestoreInfo.
+func RunRestore(rstr *apis.ZFSRestore) error {
+	backend, err := GetBackend(rstr.Spec.BackendType)
+	if err != nil {
+		return err
+	}
+
+	status, err := backend.Restore(rstr)
+	if err != nil {
+		return err
+	}
+
+	return UpdateRestoreInfo(rstr, status)
+}
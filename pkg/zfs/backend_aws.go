@@ -0,0 +1,383 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zfs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	apis "github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1"
+	"k8s.io/klog"
+)
+
+// ebsSnapshotClient is the subset of the AWS EBS API this backend needs,
+// mirroring gceDiskClient so both cloud backends can be faked in tests
+// without pulling the real cloud SDKs into the backup codepath.
+type ebsSnapshotClient interface {
+	// CreateSnapshotFromDevice copies devicePath's bytes into an EBS
+	// snapshot and returns the resulting snapshot ID.
+	CreateSnapshotFromDevice(region, devicePath, description string) (snapshotID string, err error)
+
+	// WaitForSnapshot blocks until the snapshot reaches the "completed"
+	// state.
+	WaitForSnapshot(region, snapshotID string) error
+
+	// CreateVolumeFromSnapshot provisions a new EBS volume from
+	// snapshotID and writes its bytes into devicePath.
+	CreateVolumeFromSnapshot(region, snapshotID, devicePath string) error
+}
+
+// awsEBSBackend is the SnapshotBackend that ships a ZFS dataset's blocks to
+// an AWS EBS snapshot and restores from one, the same way gcePDBackend
+// does for GCE PD.
+type awsEBSBackend struct {
+	mu      sync.Mutex
+	clients map[string]ebsSnapshotClient
+}
+
+func init() {
+	RegisterBackend(&awsEBSBackend{})
+}
+
+func (b *awsEBSBackend) Name() string {
+	return BackendTypeAWSEBS
+}
+
+// clientFor lazily builds the real AWS client for the Secret named by
+// secretName, caching it per secret name so two ZFSBackup/ZFSRestore CRs
+// referencing different Secrets (different AWS accounts) each get their
+// own client instead of silently sharing the first one built. Tests
+// bypass this by populating b.clients directly.
+func (b *awsEBSBackend) clientFor(secretName string) (ebsSnapshotClient, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if client, ok := b.clients[secretName]; ok {
+		return client, nil
+	}
+
+	secret, err := fetchCredentialSecret(secretName)
+	if err != nil {
+		return nil, fmt.Errorf("aws ebs backend: %v", err)
+	}
+
+	client, err := newEBSSnapshotClient(
+		string(secret.Data[AWSAccessKeyIDSecretKey]),
+		string(secret.Data[AWSSecretAccessKeySecretKey]),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("aws ebs backend: building client from secret %q: %v", secretName, err)
+	}
+
+	if b.clients == nil {
+		b.clients = map[string]ebsSnapshotClient{}
+	}
+	b.clients[secretName] = client
+
+	return client, nil
+}
+
+func (b *awsEBSBackend) Backup(bkp *apis.ZFSBackup) (apis.ZFSBackupStatus, error) {
+	status := bkp.Status
+
+	client, err := b.clientFor(bkp.Spec.CredentialSecretName)
+	if err != nil {
+		return status, err
+	}
+
+	devicePath := zvolDevicePath(bkp.Spec.VolumeName)
+
+	if err := runCmd("zfs", "snapshot", bkp.Spec.VolumeName+"@"+bkp.Spec.SnapName); err != nil {
+		return status, fmt.Errorf("aws ebs backend: zfs snapshot failed: %v", err)
+	}
+
+	snapshotID, err := client.CreateSnapshotFromDevice(bkp.Spec.BackupDest, devicePath, bkp.Name)
+	if err != nil {
+		return status, fmt.Errorf("aws ebs backend: create snapshot failed: %v", err)
+	}
+
+	if err := client.WaitForSnapshot(bkp.Spec.BackupDest, snapshotID); err != nil {
+		return status, fmt.Errorf("aws ebs backend: snapshot %s did not complete: %v", snapshotID, err)
+	}
+
+	status.SnapName = bkp.Spec.SnapName
+	status.EBSSnapshotID = snapshotID
+	klog.Infof("aws ebs backend: backed up %s to snapshot %s", bkp.Spec.VolumeName, snapshotID)
+
+	return status, nil
+}
+
+func (b *awsEBSBackend) Restore(rstr *apis.ZFSRestore) (apis.ZFSRestoreStatus, error) {
+	status := rstr.Status
+
+	client, err := b.clientFor(rstr.Spec.CredentialSecretName)
+	if err != nil {
+		return status, err
+	}
+
+	devicePath := zvolDevicePath(rstr.Spec.VolumeName)
+
+	if err := client.CreateVolumeFromSnapshot(rstr.Spec.RestoreSrc, rstr.Spec.EBSSnapshotID, devicePath); err != nil {
+		return status, fmt.Errorf("aws ebs backend: restoring volume from %s failed: %v", rstr.Spec.EBSSnapshotID, err)
+	}
+
+	status.State = ZFSStatusReady
+	klog.Infof("aws ebs backend: restored %s from %s", rstr.Spec.VolumeName, rstr.Spec.EBSSnapshotID)
+
+	return status, nil
+}
+
+// realEBSSnapshotClient is the ebsSnapshotClient backed by the actual AWS
+// EC2 API, authenticated with the access key pair pulled from the
+// referenced Secret.
+type realEBSSnapshotClient struct {
+	creds *credentials.Credentials
+}
+
+// newEBSSnapshotClient builds an ebsSnapshotClient authenticated with the
+// given AWS access key pair.
+func newEBSSnapshotClient(accessKeyID, secretAccessKey string) (ebsSnapshotClient, error) {
+	if accessKeyID == "" || secretAccessKey == "" {
+		return nil, fmt.Errorf("secret keys %q/%q must both be set", AWSAccessKeyIDSecretKey, AWSSecretAccessKeySecretKey)
+	}
+
+	return &realEBSSnapshotClient{
+		creds: credentials.NewStaticCredentials(accessKeyID, secretAccessKey, ""),
+	}, nil
+}
+
+func (c *realEBSSnapshotClient) ec2Client(region string) (*ec2.EC2, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String(region),
+		Credentials: c.creds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ec2.New(sess), nil
+}
+
+// ec2MetadataBaseURL is the IMDSv1 base URL for the instance this code is
+// running on. Used to discover the local instance ID and availability
+// zone so a staging/restored EBS volume can be attached here.
+const ec2MetadataBaseURL = "http://169.254.169.254/latest/meta-data/"
+
+func ec2MetadataGet(path string) (string, error) {
+	resp, err := http.Get(ec2MetadataBaseURL + path)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("instance metadata %s: unexpected status %s", path, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// ec2InstanceIdentity returns the instance ID and availability zone of the
+// instance this code is running on.
+func ec2InstanceIdentity() (instanceID, availabilityZone string, err error) {
+	instanceID, err = ec2MetadataGet("instance-id")
+	if err != nil {
+		return "", "", err
+	}
+	availabilityZone, err = ec2MetadataGet("placement/availability-zone")
+	if err != nil {
+		return "", "", err
+	}
+	return instanceID, availabilityZone, nil
+}
+
+// deviceSizeGiB returns the size of the block device at devicePath,
+// rounded up to whole GiB, with a 1 GiB floor to satisfy the EBS minimum
+// volume size.
+func deviceSizeGiB(devicePath string) (int64, error) {
+	f, err := os.Open(devicePath)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	const giB = 1 << 30
+	gib := (size + giB - 1) / giB
+	if gib < 1 {
+		gib = 1
+	}
+	return gib, nil
+}
+
+// stagingDeviceName is the device name a staging/restored EBS volume is
+// attached as. It only has to be free on the instance this code runs on,
+// which is reused for exactly one attach/dd/detach at a time per backend.
+const stagingDeviceName = "/dev/sdz"
+
+// CreateSnapshotFromDevice ships devicePath's bytes to EBS by creating a
+// throwaway volume in the local instance's availability zone, attaching
+// it here, `dd`ing the bytes across, then snapshotting and deleting the
+// throwaway volume -- EBS has no API to snapshot an arbitrary local file
+// directly, so the volume is the real source of the returned snapshot.
+func (c *realEBSSnapshotClient) CreateSnapshotFromDevice(region, devicePath, description string) (string, error) {
+	svc, err := c.ec2Client(region)
+	if err != nil {
+		return "", err
+	}
+
+	instanceID, az, err := ec2InstanceIdentity()
+	if err != nil {
+		return "", fmt.Errorf("determining local instance identity: %v", err)
+	}
+
+	sizeGiB, err := deviceSizeGiB(devicePath)
+	if err != nil {
+		return "", fmt.Errorf("sizing %s: %v", devicePath, err)
+	}
+
+	volOut, err := svc.CreateVolume(&ec2.CreateVolumeInput{
+		AvailabilityZone: aws.String(az),
+		Size:             aws.Int64(sizeGiB),
+	})
+	if err != nil {
+		return "", fmt.Errorf("creating staging volume: %v", err)
+	}
+	volumeID := aws.StringValue(volOut.VolumeId)
+	defer func() {
+		if _, derr := svc.DeleteVolume(&ec2.DeleteVolumeInput{VolumeId: aws.String(volumeID)}); derr != nil {
+			klog.Warningf("aws ebs backend: cleaning up staging volume %s: %v", volumeID, derr)
+		}
+	}()
+
+	if err := svc.WaitUntilVolumeAvailable(&ec2.DescribeVolumesInput{VolumeIds: []*string{aws.String(volumeID)}}); err != nil {
+		return "", fmt.Errorf("staging volume %s never became available: %v", volumeID, err)
+	}
+
+	if _, err := svc.AttachVolume(&ec2.AttachVolumeInput{
+		VolumeId:   aws.String(volumeID),
+		InstanceId: aws.String(instanceID),
+		Device:     aws.String(stagingDeviceName),
+	}); err != nil {
+		return "", fmt.Errorf("attaching staging volume %s: %v", volumeID, err)
+	}
+	if err := svc.WaitUntilVolumeInUse(&ec2.DescribeVolumesInput{VolumeIds: []*string{aws.String(volumeID)}}); err != nil {
+		return "", fmt.Errorf("staging volume %s never became attached: %v", volumeID, err)
+	}
+
+	ddErr := runCmd("dd", "if="+devicePath, "of="+stagingDeviceName, "bs=1M", "conv=fsync")
+
+	if _, err := svc.DetachVolume(&ec2.DetachVolumeInput{VolumeId: aws.String(volumeID)}); err != nil {
+		klog.Warningf("aws ebs backend: detaching staging volume %s: %v", volumeID, err)
+	} else if err := svc.WaitUntilVolumeAvailable(&ec2.DescribeVolumesInput{VolumeIds: []*string{aws.String(volumeID)}}); err != nil {
+		klog.Warningf("aws ebs backend: staging volume %s never finished detaching: %v", volumeID, err)
+	}
+
+	if ddErr != nil {
+		return "", fmt.Errorf("copying %s to staging volume %s: %v", devicePath, volumeID, ddErr)
+	}
+
+	out, err := svc.CreateSnapshot(&ec2.CreateSnapshotInput{
+		VolumeId:    aws.String(volumeID),
+		Description: aws.String(description),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.StringValue(out.SnapshotId), nil
+}
+
+func (c *realEBSSnapshotClient) WaitForSnapshot(region, snapshotID string) error {
+	svc, err := c.ec2Client(region)
+	if err != nil {
+		return err
+	}
+
+	return svc.WaitUntilSnapshotCompleted(&ec2.DescribeSnapshotsInput{
+		SnapshotIds: []*string{aws.String(snapshotID)},
+	})
+}
+
+// CreateVolumeFromSnapshot provisions a new EBS volume from snapshotID in
+// the local instance's availability zone, attaches it here, `dd`s its
+// bytes into devicePath, then detaches and deletes the throwaway volume.
+func (c *realEBSSnapshotClient) CreateVolumeFromSnapshot(region, snapshotID, devicePath string) error {
+	svc, err := c.ec2Client(region)
+	if err != nil {
+		return err
+	}
+
+	instanceID, az, err := ec2InstanceIdentity()
+	if err != nil {
+		return fmt.Errorf("determining local instance identity: %v", err)
+	}
+
+	volOut, err := svc.CreateVolume(&ec2.CreateVolumeInput{
+		AvailabilityZone: aws.String(az),
+		SnapshotId:       aws.String(snapshotID),
+	})
+	if err != nil {
+		return fmt.Errorf("creating volume from snapshot %s: %v", snapshotID, err)
+	}
+	volumeID := aws.StringValue(volOut.VolumeId)
+	defer func() {
+		if _, derr := svc.DeleteVolume(&ec2.DeleteVolumeInput{VolumeId: aws.String(volumeID)}); derr != nil {
+			klog.Warningf("aws ebs backend: cleaning up restored volume %s: %v", volumeID, derr)
+		}
+	}()
+
+	if err := svc.WaitUntilVolumeAvailable(&ec2.DescribeVolumesInput{VolumeIds: []*string{aws.String(volumeID)}}); err != nil {
+		return fmt.Errorf("restored volume %s never became available: %v", volumeID, err)
+	}
+
+	if _, err := svc.AttachVolume(&ec2.AttachVolumeInput{
+		VolumeId:   aws.String(volumeID),
+		InstanceId: aws.String(instanceID),
+		Device:     aws.String(stagingDeviceName),
+	}); err != nil {
+		return fmt.Errorf("attaching restored volume %s: %v", volumeID, err)
+	}
+	if err := svc.WaitUntilVolumeInUse(&ec2.DescribeVolumesInput{VolumeIds: []*string{aws.String(volumeID)}}); err != nil {
+		return fmt.Errorf("restored volume %s never became attached: %v", volumeID, err)
+	}
+
+	ddErr := runCmd("dd", "if="+stagingDeviceName, "of="+devicePath, "bs=1M", "conv=fsync")
+
+	if _, err := svc.DetachVolume(&ec2.DetachVolumeInput{VolumeId: aws.String(volumeID)}); err != nil {
+		klog.Warningf("aws ebs backend: detaching restored volume %s: %v", volumeID, err)
+	} else if err := svc.WaitUntilVolumeAvailable(&ec2.DescribeVolumesInput{VolumeIds: []*string{aws.String(volumeID)}}); err != nil {
+		klog.Warningf("aws ebs backend: restored volume %s never finished detaching: %v", volumeID, err)
+	}
+
+	if ddErr != nil {
+		return fmt.Errorf("copying restored volume %s to %s: %v", volumeID, devicePath, ddErr)
+	}
+	return nil
+}
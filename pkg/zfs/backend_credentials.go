@@ -0,0 +1,81 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+const (
+	// GCECredentialsSecretKey is the key within the referenced Secret that
+	// holds the GCE service account JSON key.
+	GCECredentialsSecretKey string = "credentials.json"
+	// AWSAccessKeyIDSecretKey is the key within the referenced Secret that
+	// holds the AWS access key ID.
+	AWSAccessKeyIDSecretKey string = "access-key-id"
+	// AWSSecretAccessKeySecretKey is the key within the referenced Secret
+	// that holds the AWS secret access key.
+	AWSSecretAccessKeySecretKey string = "secret-access-key"
+)
+
+var (
+	kubeClientsetOnce sync.Once
+	kubeClientset     kubernetes.Interface
+	kubeClientsetErr  error
+)
+
+// credentialSecretClient lazily builds the in-cluster kubernetes.Interface
+// used to fetch the Secrets referenced by ZFSBackup/ZFSRestore CRs. It is a
+// package-level singleton, same pattern as volbuilder.NewKubeclient(), so
+// every backend shares one client instead of building its own.
+func credentialSecretClient() (kubernetes.Interface, error) {
+	kubeClientsetOnce.Do(func() {
+		cfg, err := rest.InClusterConfig()
+		if err != nil {
+			kubeClientsetErr = err
+			return
+		}
+		kubeClientset, kubeClientsetErr = kubernetes.NewForConfig(cfg)
+	})
+	return kubeClientset, kubeClientsetErr
+}
+
+// fetchCredentialSecret fetches the named Secret from OpenEBSNamespace, the
+// namespace the request's "Credentials come from a referenced Secret in
+// OpenEBSNamespace" refers to.
+func fetchCredentialSecret(name string) (*corev1.Secret, error) {
+	if name == "" {
+		return nil, fmt.Errorf("credential secret name is empty")
+	}
+
+	clientset, err := credentialSecretClient()
+	if err != nil {
+		return nil, fmt.Errorf("building kube client for credential secret %q: %v", name, err)
+	}
+
+	secret, err := clientset.CoreV1().Secrets(OpenEBSNamespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("fetching credential secret %s/%s: %v", OpenEBSNamespace, name, err)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,426 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zfs
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	apis "github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1"
+	"golang.org/x/oauth2/google"
+	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/option"
+	storage "google.golang.org/api/storage/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog"
+)
+
+// gceDiskClient is the subset of the GCE compute API this backend needs.
+// It is an interface, not the generated compute.Service client directly,
+// so the backend can be unit tested with a fake.
+type gceDiskClient interface {
+	// CreateSnapshotFromDevice creates a GCE disk snapshot named
+	// snapshotName out of the raw bytes read from devicePath, staging
+	// them through stagingBucket on the way to a throwaway GCE disk, and
+	// returns the GCE operation name to poll for the final snapshot.
+	CreateSnapshotFromDevice(project, zone, stagingBucket, snapshotName, devicePath string) (opName string, err error)
+
+	// WaitForOperation checks the named operation once, without blocking:
+	// it returns nil if it is DONE, and an error if it is still running or
+	// failed. Callers poll it with gceOperationBackoff.
+	WaitForOperation(project, opName string) error
+
+	// SnapshotSelfLink returns the self-link for a completed snapshot.
+	SnapshotSelfLink(project, snapshotName string) (string, error)
+
+	// CreateDiskFromSnapshot provisions a new GCE PD from a snapshot
+	// self-link, attaches it to the instance this code is running on,
+	// and writes its bytes into devicePath via `dd` from the attached
+	// device before deleting the throwaway disk.
+	CreateDiskFromSnapshot(project, zone, diskName, snapshotSelfLink, devicePath string) error
+}
+
+// gcePDBackend is the SnapshotBackend that ships a ZFS dataset's blocks to
+// a GCE PD snapshot and restores from one. It carves a temporary block
+// device out of the zvol (`zfs snapshot` + `dd` from /dev/zvol/...) so the
+// existing dataset never has to be exported as a file.
+type gcePDBackend struct {
+	mu      sync.Mutex
+	clients map[string]gceDiskClient
+}
+
+func init() {
+	RegisterBackend(&gcePDBackend{})
+}
+
+func (b *gcePDBackend) Name() string {
+	return BackendTypeGCEPD
+}
+
+// clientFor lazily builds the real GCE client for the Secret named by
+// secretName, caching it per secret name so two ZFSBackup/ZFSRestore CRs
+// referencing different Secrets (different projects/accounts) each get
+// their own client instead of silently sharing the first one built.
+// Tests bypass this by populating b.clients directly.
+func (b *gcePDBackend) clientFor(secretName string) (gceDiskClient, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if client, ok := b.clients[secretName]; ok {
+		return client, nil
+	}
+
+	secret, err := fetchCredentialSecret(secretName)
+	if err != nil {
+		return nil, fmt.Errorf("gce pd backend: %v", err)
+	}
+
+	client, err := newGCEDiskClient(secret.Data[GCECredentialsSecretKey])
+	if err != nil {
+		return nil, fmt.Errorf("gce pd backend: building client from secret %q: %v", secretName, err)
+	}
+
+	if b.clients == nil {
+		b.clients = map[string]gceDiskClient{}
+	}
+	b.clients[secretName] = client
+
+	return client, nil
+}
+
+// gceOperationBackoff is the exponential backoff used while polling a GCE
+// snapshot/disk operation, capped so a stuck operation still fails the
+// backup/restore instead of hanging the controller indefinitely.
+var gceOperationBackoff = wait.Backoff{
+	Duration: 2 * time.Second,
+	Factor:   2.0,
+	Steps:    8,
+}
+
+func (b *gcePDBackend) Backup(bkp *apis.ZFSBackup) (apis.ZFSBackupStatus, error) {
+	status := bkp.Status
+
+	client, err := b.clientFor(bkp.Spec.CredentialSecretName)
+	if err != nil {
+		return status, err
+	}
+
+	devicePath := zvolDevicePath(bkp.Spec.VolumeName)
+	snapshotName := bkp.Name
+
+	if err := runCmd("zfs", "snapshot", bkp.Spec.VolumeName+"@"+bkp.Spec.SnapName); err != nil {
+		return status, fmt.Errorf("gce pd backend: zfs snapshot failed: %v", err)
+	}
+
+	opName, err := client.CreateSnapshotFromDevice(bkp.Spec.BackupDest, bkp.Spec.Zone, bkp.Spec.GCSStagingBucket, snapshotName, devicePath)
+	if err != nil {
+		return status, fmt.Errorf("gce pd backend: create snapshot failed: %v", err)
+	}
+
+	if err := wait.ExponentialBackoff(gceOperationBackoff, func() (bool, error) {
+		if werr := client.WaitForOperation(bkp.Spec.BackupDest, opName); werr != nil {
+			klog.Warningf("gce pd backend: operation %s not done yet: %v", opName, werr)
+			return false, nil
+		}
+		return true, nil
+	}); err != nil {
+		return status, fmt.Errorf("gce pd backend: snapshot operation %s did not complete: %v", opName, err)
+	}
+
+	selfLink, err := client.SnapshotSelfLink(bkp.Spec.BackupDest, snapshotName)
+	if err != nil {
+		return status, fmt.Errorf("gce pd backend: fetching snapshot self-link failed: %v", err)
+	}
+
+	status.SnapName = bkp.Spec.SnapName
+	status.GCESnapshotSelfLink = selfLink
+	klog.Infof("gce pd backend: backed up %s to snapshot %s", bkp.Spec.VolumeName, selfLink)
+
+	return status, nil
+}
+
+func (b *gcePDBackend) Restore(rstr *apis.ZFSRestore) (apis.ZFSRestoreStatus, error) {
+	status := rstr.Status
+
+	client, err := b.clientFor(rstr.Spec.CredentialSecretName)
+	if err != nil {
+		return status, err
+	}
+
+	devicePath := zvolDevicePath(rstr.Spec.VolumeName)
+	diskName := rstr.Name
+
+	if err := client.CreateDiskFromSnapshot(rstr.Spec.RestoreSrc, rstr.Spec.Zone, diskName, rstr.Spec.GCESnapshotSelfLink, devicePath); err != nil {
+		return status, fmt.Errorf("gce pd backend: restoring disk %s failed: %v", diskName, err)
+	}
+
+	status.State = ZFSStatusReady
+	klog.Infof("gce pd backend: restored %s from %s", rstr.Spec.VolumeName, rstr.Spec.GCESnapshotSelfLink)
+
+	return status, nil
+}
+
+// zvolDevicePath returns the raw block device path for a zvol-backed
+// ZFSVolume, used as the `dd` source/destination when shipping bytes to or
+// from a cloud disk snapshot.
+func zvolDevicePath(volumeName string) string {
+	return "/dev/zvol/" + volumeName
+}
+
+// runCmd runs a local command, logging its combined output on failure so
+// `zfs snapshot`/`dd` errors show up in the node agent's logs. It is a
+// variable so tests can replace it.
+var runCmd = func(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %v: %v: %s", name, args, err, string(out))
+	}
+	return nil
+}
+
+// realGCEDiskClient is the gceDiskClient backed by the actual GCE compute
+// and GCS storage APIs, authenticated with the service account JSON key
+// pulled from the referenced Secret.
+type realGCEDiskClient struct {
+	svc     *compute.Service
+	storage *storage.Service
+}
+
+// newGCEDiskClient builds a gceDiskClient authenticated with the given
+// service account JSON key.
+func newGCEDiskClient(credentialsJSON []byte) (gceDiskClient, error) {
+	if len(credentialsJSON) == 0 {
+		return nil, fmt.Errorf("secret key %q is empty", GCECredentialsSecretKey)
+	}
+
+	ctx := context.Background()
+	creds, err := google.CredentialsFromJSON(ctx, credentialsJSON, compute.ComputeScope, storage.DevstorageReadWriteScope)
+	if err != nil {
+		return nil, fmt.Errorf("parsing service account credentials: %v", err)
+	}
+
+	svc, err := compute.NewService(ctx, option.WithCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("building compute service: %v", err)
+	}
+
+	storageSvc, err := storage.NewService(ctx, option.WithCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("building storage service: %v", err)
+	}
+
+	return &realGCEDiskClient{svc: svc, storage: storageSvc}, nil
+}
+
+// gceMetadataGet fetches a single value from the GCE instance metadata
+// server, used to discover the identity of the node this code is running
+// on so a restored disk can be attached to it.
+func gceMetadataGet(path string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, "http://metadata.google.internal/computeMetadata/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("instance metadata %s: unexpected status %s", path, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// stageDeviceToGCS uploads the raw bytes at devicePath to bucket as
+// objectName, returning the object's https://storage.googleapis.com URL,
+// which a GCE image can be built from.
+func (c *realGCEDiskClient) stageDeviceToGCS(bucket, objectName, devicePath string) (string, error) {
+	f, err := os.Open(devicePath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if _, err := c.storage.Objects.Insert(bucket, &storage.Object{Name: objectName}).Media(f).Do(); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucket, objectName), nil
+}
+
+func (c *realGCEDiskClient) waitForZoneOperation(project, zone, opName string) error {
+	return wait.ExponentialBackoff(gceOperationBackoff, func() (bool, error) {
+		op, err := c.svc.ZoneOperations.Get(project, zone, opName).Do()
+		if err != nil {
+			return false, err
+		}
+		if op.Status != "DONE" {
+			return false, nil
+		}
+		if op.Error != nil && len(op.Error.Errors) > 0 {
+			return false, fmt.Errorf("operation %s failed: %s", opName, op.Error.Errors[0].Message)
+		}
+		return true, nil
+	})
+}
+
+// CreateSnapshotFromDevice ships devicePath's bytes to GCE by staging them
+// through GCS, building a throwaway GCE image and disk from that staged
+// object, and finally snapshotting that disk -- GCE has no API to snapshot
+// an arbitrary local file directly, so the disk is the real source of the
+// returned snapshot operation.
+func (c *realGCEDiskClient) CreateSnapshotFromDevice(project, zone, stagingBucket, snapshotName, devicePath string) (string, error) {
+	objectName := snapshotName + ".raw"
+	sourceURI, err := c.stageDeviceToGCS(stagingBucket, objectName, devicePath)
+	if err != nil {
+		return "", fmt.Errorf("staging %s to gs://%s/%s: %v", devicePath, stagingBucket, objectName, err)
+	}
+	defer func() {
+		if derr := c.storage.Objects.Delete(stagingBucket, objectName).Do(); derr != nil {
+			klog.Warningf("gce pd backend: cleaning up staged object gs://%s/%s: %v", stagingBucket, objectName, derr)
+		}
+	}()
+
+	imageName := snapshotName + "-staging-image"
+	imgOp, err := c.svc.Images.Insert(project, &compute.Image{
+		Name:    imageName,
+		RawDisk: &compute.ImageRawDisk{Source: sourceURI},
+	}).Do()
+	if err != nil {
+		return "", fmt.Errorf("creating staging image %s: %v", imageName, err)
+	}
+	if err := wait.ExponentialBackoff(gceOperationBackoff, func() (bool, error) {
+		return c.WaitForOperation(project, imgOp.Name) == nil, nil
+	}); err != nil {
+		return "", fmt.Errorf("staging image %s did not complete: %v", imageName, err)
+	}
+	defer func() {
+		if derr := c.svc.Images.Delete(project, imageName).Do(); derr != nil {
+			klog.Warningf("gce pd backend: cleaning up staging image %s: %v", imageName, derr)
+		}
+	}()
+
+	diskName := snapshotName + "-staging-disk"
+	diskOp, err := c.svc.Disks.Insert(project, zone, &compute.Disk{
+		Name:        diskName,
+		SourceImage: fmt.Sprintf("global/images/%s", imageName),
+	}).Do()
+	if err != nil {
+		return "", fmt.Errorf("creating staging disk %s: %v", diskName, err)
+	}
+	if err := c.waitForZoneOperation(project, zone, diskOp.Name); err != nil {
+		return "", fmt.Errorf("staging disk %s did not complete: %v", diskName, err)
+	}
+	defer func() {
+		if derr := c.svc.Disks.Delete(project, zone, diskName).Do(); derr != nil {
+			klog.Warningf("gce pd backend: cleaning up staging disk %s: %v", diskName, derr)
+		}
+	}()
+
+	op, err := c.svc.Snapshots.Insert(project, &compute.Snapshot{
+		Name:       snapshotName,
+		SourceDisk: fmt.Sprintf("projects/%s/zones/%s/disks/%s", project, zone, diskName),
+	}).Do()
+	if err != nil {
+		return "", err
+	}
+	return op.Name, nil
+}
+
+func (c *realGCEDiskClient) WaitForOperation(project, opName string) error {
+	op, err := c.svc.GlobalOperations.Get(project, opName).Do()
+	if err != nil {
+		return err
+	}
+	if op.Status != "DONE" {
+		return fmt.Errorf("operation %s is %s", opName, op.Status)
+	}
+	if op.Error != nil && len(op.Error.Errors) > 0 {
+		return fmt.Errorf("operation %s failed: %s", opName, op.Error.Errors[0].Message)
+	}
+	return nil
+}
+
+func (c *realGCEDiskClient) SnapshotSelfLink(project, snapshotName string) (string, error) {
+	snap, err := c.svc.Snapshots.Get(project, snapshotName).Do()
+	if err != nil {
+		return "", err
+	}
+	return snap.SelfLink, nil
+}
+
+// CreateDiskFromSnapshot provisions a new GCE PD from snapshotSelfLink,
+// attaches it to the instance this code is running on (discovered via the
+// metadata server), `dd`s its bytes into devicePath, then detaches and
+// deletes the throwaway disk.
+func (c *realGCEDiskClient) CreateDiskFromSnapshot(project, zone, diskName, snapshotSelfLink, devicePath string) error {
+	op, err := c.svc.Disks.Insert(project, zone, &compute.Disk{
+		Name:           diskName,
+		SourceSnapshot: snapshotSelfLink,
+	}).Do()
+	if err != nil {
+		return err
+	}
+	if err := c.waitForZoneOperation(project, zone, op.Name); err != nil {
+		return fmt.Errorf("restored disk %s did not complete: %v", diskName, err)
+	}
+	defer func() {
+		if derr := c.svc.Disks.Delete(project, zone, diskName).Do(); derr != nil {
+			klog.Warningf("gce pd backend: cleaning up restored disk %s: %v", diskName, derr)
+		}
+	}()
+
+	instanceName, err := gceMetadataGet("instance/name")
+	if err != nil {
+		return fmt.Errorf("determining local instance name: %v", err)
+	}
+
+	attachOp, err := c.svc.Instances.AttachDisk(project, zone, instanceName, &compute.AttachedDisk{
+		Source: fmt.Sprintf("projects/%s/zones/%s/disks/%s", project, zone, diskName),
+	}).Do()
+	if err != nil {
+		return fmt.Errorf("attaching restored disk %s to %s: %v", diskName, instanceName, err)
+	}
+	if err := c.waitForZoneOperation(project, zone, attachOp.Name); err != nil {
+		return fmt.Errorf("attaching restored disk %s to %s did not complete: %v", diskName, instanceName, err)
+	}
+
+	attachedDevice := "/dev/disk/by-id/google-" + diskName
+	ddErr := runCmd("dd", "if="+attachedDevice, "of="+devicePath, "bs=1M", "conv=fsync")
+
+	if detachOp, derr := c.svc.Instances.DetachDisk(project, zone, instanceName, diskName).Do(); derr != nil {
+		klog.Warningf("gce pd backend: detaching restored disk %s from %s: %v", diskName, instanceName, derr)
+	} else if derr := c.waitForZoneOperation(project, zone, detachOp.Name); derr != nil {
+		klog.Warningf("gce pd backend: detaching restored disk %s from %s did not complete: %v", diskName, instanceName, derr)
+	}
+
+	if ddErr != nil {
+		return fmt.Errorf("copying disk %s to %s: %v", diskName, devicePath, ddErr)
+	}
+	return nil
+}
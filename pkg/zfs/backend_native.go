@@ -0,0 +1,42 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zfs
+
+import (
+	apis "github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1"
+)
+
+// nativeBackend is the SnapshotBackend for the original `zfs send | remote`
+// pipeline. The node agent already performs the actual send/recv via the
+// zfs command wrappers before calling UpdateBkpInfo/UpdateRestoreInfo, so
+// this backend only has to report that status back through the same
+// interface the cloud backends use.
+type nativeBackend struct{}
+
+func init() {
+	RegisterBackend(&nativeBackend{})
+}
+
+func (b *nativeBackend) Name() string {
+	return BackendTypeZFS
+}
+
+func (b *nativeBackend) Backup(bkp *apis.ZFSBackup) (apis.ZFSBackupStatus, error) {
+	return bkp.Status, nil
+}
+
+func (b *nativeBackend) Restore(rstr *apis.ZFSRestore) (apis.ZFSRestoreStatus, error) {
+	return rstr.Status, nil
+}
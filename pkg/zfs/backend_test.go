@@ -0,0 +1,127 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zfs
+
+import (
+	"testing"
+
+	apis "github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1"
+)
+
+type fakeGCEDiskClient struct {
+	selfLink string
+}
+
+func (f *fakeGCEDiskClient) CreateSnapshotFromDevice(project, zone, stagingBucket, snapshotName, devicePath string) (string, error) {
+	return "op-1", nil
+}
+
+func (f *fakeGCEDiskClient) WaitForOperation(project, opName string) error {
+	return nil
+}
+
+func (f *fakeGCEDiskClient) SnapshotSelfLink(project, snapshotName string) (string, error) {
+	return f.selfLink, nil
+}
+
+func (f *fakeGCEDiskClient) CreateDiskFromSnapshot(project, zone, diskName, snapshotSelfLink, devicePath string) error {
+	return nil
+}
+
+func TestGCEPDBackendBackupUsesConfiguredClient(t *testing.T) {
+	savedRunCmd := runCmd
+	defer func() { runCmd = savedRunCmd }()
+	runCmd = func(name string, args ...string) error { return nil }
+
+	backend := &gcePDBackend{clients: map[string]gceDiskClient{"": &fakeGCEDiskClient{selfLink: "projects/p/global/snapshots/snap-1"}}}
+
+	bkp := &apis.ZFSBackup{}
+	bkp.Name = "bkp-1"
+	bkp.Spec.SnapName = "snap-1"
+
+	status, err := backend.Backup(bkp)
+	if err != nil {
+		t.Fatalf("Backup() error = %v, want nil", err)
+	}
+	if status.GCESnapshotSelfLink != "projects/p/global/snapshots/snap-1" {
+		t.Errorf("status.GCESnapshotSelfLink = %q, want the fake client's self-link", status.GCESnapshotSelfLink)
+	}
+}
+
+func TestGCEPDBackendRestoreUsesConfiguredClient(t *testing.T) {
+	backend := &gcePDBackend{clients: map[string]gceDiskClient{"": &fakeGCEDiskClient{}}}
+
+	rstr := &apis.ZFSRestore{}
+	rstr.Name = "rstr-1"
+
+	status, err := backend.Restore(rstr)
+	if err != nil {
+		t.Fatalf("Restore() error = %v, want nil", err)
+	}
+	if status.State != ZFSStatusReady {
+		t.Errorf("status.State = %q, want %q", status.State, ZFSStatusReady)
+	}
+}
+
+type fakeEBSSnapshotClient struct {
+	snapshotID string
+}
+
+func (f *fakeEBSSnapshotClient) CreateSnapshotFromDevice(region, devicePath, description string) (string, error) {
+	return f.snapshotID, nil
+}
+
+func (f *fakeEBSSnapshotClient) WaitForSnapshot(region, snapshotID string) error {
+	return nil
+}
+
+func (f *fakeEBSSnapshotClient) CreateVolumeFromSnapshot(region, snapshotID, devicePath string) error {
+	return nil
+}
+
+func TestAWSEBSBackendBackupUsesConfiguredClient(t *testing.T) {
+	savedRunCmd := runCmd
+	defer func() { runCmd = savedRunCmd }()
+	runCmd = func(name string, args ...string) error { return nil }
+
+	backend := &awsEBSBackend{clients: map[string]ebsSnapshotClient{"": &fakeEBSSnapshotClient{snapshotID: "snap-abc"}}}
+
+	bkp := &apis.ZFSBackup{}
+	bkp.Name = "bkp-1"
+	bkp.Spec.SnapName = "snap-1"
+
+	status, err := backend.Backup(bkp)
+	if err != nil {
+		t.Fatalf("Backup() error = %v, want nil", err)
+	}
+	if status.EBSSnapshotID != "snap-abc" {
+		t.Errorf("status.EBSSnapshotID = %q, want the fake client's snapshot id", status.EBSSnapshotID)
+	}
+}
+
+func TestAWSEBSBackendRestoreUsesConfiguredClient(t *testing.T) {
+	backend := &awsEBSBackend{clients: map[string]ebsSnapshotClient{"": &fakeEBSSnapshotClient{}}}
+
+	rstr := &apis.ZFSRestore{}
+	rstr.Name = "rstr-1"
+
+	status, err := backend.Restore(rstr)
+	if err != nil {
+		t.Fatalf("Restore() error = %v, want nil", err)
+	}
+	if status.State != ZFSStatusReady {
+		t.Errorf("status.State = %q, want %q", status.State, ZFSStatusReady)
+	}
+}
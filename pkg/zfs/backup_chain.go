@@ -0,0 +1,154 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zfs
+
+import (
+	"fmt"
+	"time"
+
+	apis "github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// IsIncrementalBackup tells whether bkp chains off a previous snapshot, in
+// which case the node agent must run `zfs send -i <prev> <curr>` instead
+// of a full send.
+func IsIncrementalBackup(bkp *apis.ZFSBackup) bool {
+	return bkp.Spec.PrevSnapName != ""
+}
+
+// ValidateIncrementalBackup checks that an incremental ZFSBackup's parent
+// snapshot still exists on the source node before the send is attempted.
+// A full backup (Spec.PrevSnapName unset) always validates.
+func ValidateIncrementalBackup(bkp *apis.ZFSBackup) error {
+	if !IsIncrementalBackup(bkp) {
+		return nil
+	}
+
+	if _, err := GetZFSSnapshot(bkp.Spec.PrevSnapName); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return fmt.Errorf("incremental backup %s: parent snapshot %q no longer exists", bkp.Name, bkp.Spec.PrevSnapName)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// SnapshotSendArgs returns the `zfs send` arguments the node agent should
+// use for bkp: an incremental send (`-i <prev> <curr>`) when
+// Spec.PrevSnapName is set, otherwise a full send of the current snapshot.
+func SnapshotSendArgs(bkp *apis.ZFSBackup) []string {
+	curr := bkp.Spec.VolumeName + "@" + bkp.Spec.SnapName
+
+	if IsIncrementalBackup(bkp) {
+		prev := bkp.Spec.VolumeName + "@" + bkp.Spec.PrevSnapName
+		return []string{"send", "-i", prev, curr}
+	}
+
+	return []string{"send", curr}
+}
+
+// RecordBackupMetrics fills in the size/duration metrics for a single link
+// in a backup chain. The node agent calls this right after a `zfs send`
+// (full or incremental) completes, before UpdateBkpInfo persists status,
+// so GetBackupChain's callers can see per-link cost when replaying a
+// restore.
+func RecordBackupMetrics(status *apis.ZFSBackupStatus, sentBytes int64, duration time.Duration) {
+	status.SentBytes = sentBytes
+	status.SendDuration = duration.String()
+}
+
+// GetBackupChain returns every ZFSBackup for volumeID, ordered from the
+// full backup at the head of the chain to the most recent delta, so a
+// restore can replay them full-then-deltas in sequence. The order is built
+// by walking the actual Spec.PrevSnapName parent links rather than
+// CreationTimestamp -- two backups created close together, or on nodes
+// with clock skew, can otherwise sort in an order that doesn't match the
+// real chain. It is an error if the links don't form exactly one chain
+// covering every backup found for volumeID (more than one full backup, two
+// backups chained off the same parent snapshot, or a dangling link all
+// count as broken).
+func GetBackupChain(volumeID string) ([]*apis.ZFSBackup, error) {
+	bkpList, err := ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	matching := make([]*apis.ZFSBackup, 0, len(bkpList.Items))
+	for i := range bkpList.Items {
+		bkp := &bkpList.Items[i]
+		if bkp.Labels[ZFSVolKey] != volumeID && bkp.Spec.VolumeName != volumeID {
+			continue
+		}
+		matching = append(matching, bkp)
+	}
+
+	return orderBackupChain(volumeID, matching)
+}
+
+// orderBackupChain walks bkps' Spec.PrevSnapName parent links to produce
+// the dependency order GetBackupChain promises, starting from the one
+// full backup (Spec.PrevSnapName unset) and following each link to the
+// backup chained off its Spec.SnapName. Split out from GetBackupChain so
+// the ordering logic is unit-testable without a kube client.
+func orderBackupChain(volumeID string, bkps []*apis.ZFSBackup) ([]*apis.ZFSBackup, error) {
+	// byParentSnap indexes backups by the parent snapshot they chain off
+	// of (Spec.PrevSnapName), so the chain can be walked forward from the
+	// one full backup that has no parent.
+	byParentSnap := map[string]*apis.ZFSBackup{}
+	var head *apis.ZFSBackup
+
+	for _, bkp := range bkps {
+		if !IsIncrementalBackup(bkp) {
+			if head != nil {
+				return nil, fmt.Errorf("backup chain for volume %s has more than one full backup: %s and %s", volumeID, head.Name, bkp.Name)
+			}
+			head = bkp
+			continue
+		}
+
+		if existing, ok := byParentSnap[bkp.Spec.PrevSnapName]; ok {
+			return nil, fmt.Errorf("backup chain for volume %s has two backups chained off snapshot %s: %s and %s", volumeID, bkp.Spec.PrevSnapName, existing.Name, bkp.Name)
+		}
+		byParentSnap[bkp.Spec.PrevSnapName] = bkp
+	}
+
+	if len(bkps) == 0 {
+		return nil, nil
+	}
+	if head == nil {
+		return nil, fmt.Errorf("backup chain for volume %s has no full backup to start from", volumeID)
+	}
+
+	chain := make([]*apis.ZFSBackup, 0, len(bkps))
+	chain = append(chain, head)
+	visited := map[*apis.ZFSBackup]bool{head: true}
+	for curr := head; ; {
+		next, ok := byParentSnap[curr.Spec.SnapName]
+		if !ok || visited[next] {
+			break
+		}
+		visited[next] = true
+		chain = append(chain, next)
+		curr = next
+	}
+
+	if len(chain) != len(bkps) {
+		return nil, fmt.Errorf("backup chain for volume %s is broken: found %d backups but only %d are linked by PrevSnapName", volumeID, len(bkps), len(chain))
+	}
+
+	return chain, nil
+}
@@ -0,0 +1,141 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zfs
+
+import (
+	"testing"
+	"time"
+
+	apis "github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1"
+)
+
+func TestSnapshotSendArgs(t *testing.T) {
+	full := &apis.ZFSBackup{}
+	full.Spec.VolumeName = "vol-1"
+	full.Spec.SnapName = "snap-1"
+
+	if got := SnapshotSendArgs(full); len(got) != 2 || got[1] != "vol-1@snap-1" {
+		t.Errorf("SnapshotSendArgs(full) = %v, want a full send of vol-1@snap-1", got)
+	}
+
+	delta := &apis.ZFSBackup{}
+	delta.Spec.VolumeName = "vol-1"
+	delta.Spec.SnapName = "snap-2"
+	delta.Spec.PrevSnapName = "snap-1"
+
+	got := SnapshotSendArgs(delta)
+	want := []string{"send", "-i", "vol-1@snap-1", "vol-1@snap-2"}
+	if len(got) != len(want) {
+		t.Fatalf("SnapshotSendArgs(delta) = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("SnapshotSendArgs(delta)[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestOrderBackupChain(t *testing.T) {
+	full := &apis.ZFSBackup{}
+	full.Name = "bkp-full"
+	full.Spec.SnapName = "snap-1"
+
+	delta1 := &apis.ZFSBackup{}
+	delta1.Name = "bkp-delta-1"
+	delta1.Spec.PrevSnapName = "snap-1"
+	delta1.Spec.SnapName = "snap-2"
+
+	delta2 := &apis.ZFSBackup{}
+	delta2.Name = "bkp-delta-2"
+	delta2.Spec.PrevSnapName = "snap-2"
+	delta2.Spec.SnapName = "snap-3"
+
+	t.Run("orders a full-plus-delta chain regardless of input order", func(t *testing.T) {
+		chain, err := orderBackupChain("vol-1", []*apis.ZFSBackup{delta2, full, delta1})
+		if err != nil {
+			t.Fatalf("orderBackupChain() error = %v, want nil", err)
+		}
+		want := []string{"bkp-full", "bkp-delta-1", "bkp-delta-2"}
+		if len(chain) != len(want) {
+			t.Fatalf("orderBackupChain() = %v, want %v", chain, want)
+		}
+		for i := range want {
+			if chain[i].Name != want[i] {
+				t.Errorf("orderBackupChain()[%d] = %q, want %q", i, chain[i].Name, want[i])
+			}
+		}
+	})
+
+	t.Run("no backups returns nil, nil", func(t *testing.T) {
+		chain, err := orderBackupChain("vol-1", nil)
+		if err != nil || chain != nil {
+			t.Errorf("orderBackupChain(nil) = (%v, %v), want (nil, nil)", chain, err)
+		}
+	})
+
+	t.Run("no full backup is an error", func(t *testing.T) {
+		_, err := orderBackupChain("vol-1", []*apis.ZFSBackup{delta1})
+		if err == nil {
+			t.Fatal("orderBackupChain() error = nil, want an error about a missing full backup")
+		}
+	})
+
+	t.Run("two full backups is an error", func(t *testing.T) {
+		otherFull := &apis.ZFSBackup{}
+		otherFull.Name = "bkp-full-2"
+		otherFull.Spec.SnapName = "snap-9"
+
+		_, err := orderBackupChain("vol-1", []*apis.ZFSBackup{full, otherFull})
+		if err == nil {
+			t.Fatal("orderBackupChain() error = nil, want an error about more than one full backup")
+		}
+	})
+
+	t.Run("two backups chained off the same parent snapshot is an error", func(t *testing.T) {
+		sibling := &apis.ZFSBackup{}
+		sibling.Name = "bkp-sibling"
+		sibling.Spec.PrevSnapName = "snap-1"
+		sibling.Spec.SnapName = "snap-2b"
+
+		_, err := orderBackupChain("vol-1", []*apis.ZFSBackup{full, delta1, sibling})
+		if err == nil {
+			t.Fatal("orderBackupChain() error = nil, want an error about two backups chained off the same snapshot")
+		}
+	})
+
+	t.Run("a dangling link is an error", func(t *testing.T) {
+		orphan := &apis.ZFSBackup{}
+		orphan.Name = "bkp-orphan"
+		orphan.Spec.PrevSnapName = "snap-does-not-exist"
+		orphan.Spec.SnapName = "snap-99"
+
+		_, err := orderBackupChain("vol-1", []*apis.ZFSBackup{full, orphan})
+		if err == nil {
+			t.Fatal("orderBackupChain() error = nil, want an error about a broken chain")
+		}
+	})
+}
+
+func TestRecordBackupMetrics(t *testing.T) {
+	status := &apis.ZFSBackupStatus{}
+	RecordBackupMetrics(status, 1024, 2*time.Second)
+
+	if status.SentBytes != 1024 {
+		t.Errorf("status.SentBytes = %d, want 1024", status.SentBytes)
+	}
+	if status.SendDuration != (2 * time.Second).String() {
+		t.Errorf("status.SendDuration = %q, want %q", status.SendDuration, (2 * time.Second).String())
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resource wraps the CR getters in pkg/zfs so that a cluster mid
+// upgrade -- where some ZFSVolume/ZFSSnapshot CRs may still be stored under
+// an older group version, or where a CRD's storage version has drifted --
+// keeps working without operator intervention. A Getter first tries the
+// current openebs.io/zfs/v1 API and, on NotFound/NoKindMatch, falls back to
+// a registered older version and converts the result up to v1.
+package resource
+
+import (
+	"fmt"
+	"sync"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/retry"
+)
+
+// errUnexpectedType builds the error a ConversionFunc should return when
+// the object handed to it isn't the legacy type it was registered for.
+func errUnexpectedType(gvk schema.GroupVersionKind, obj runtime.Object) error {
+	return fmt.Errorf("resource: expected %s, got %T", gvk, obj)
+}
+
+// ConversionFunc upgrades an older-version object to its current v1
+// equivalent.
+type ConversionFunc func(runtime.Object) (runtime.Object, error)
+
+// conversions maps a legacy GroupVersionKind to the func that upgrades an
+// object of that kind to v1.
+var conversions = map[schema.GroupVersionKind]ConversionFunc{}
+
+// RegisterConversion registers the upgrade path for a legacy GVK. Callers
+// normally do this from an init() next to the ConversionFunc itself.
+func RegisterConversion(gvk schema.GroupVersionKind, fn ConversionFunc) {
+	conversions[gvk] = fn
+}
+
+// fallbackHits counts how many Get calls had to fall back to a legacy
+// version, broken down by the legacy GVK. Operators can watch this drop to
+// zero as a signal that a version migration has completed. Guarded by
+// fallbackHitsMu since Get is called from concurrent reconcile goroutines.
+var (
+	fallbackHitsMu sync.Mutex
+	fallbackHits   = map[schema.GroupVersionKind]int{}
+)
+
+// recordFallbackHit increments the fallback counter for gvk.
+func recordFallbackHit(gvk schema.GroupVersionKind) {
+	fallbackHitsMu.Lock()
+	defer fallbackHitsMu.Unlock()
+	fallbackHits[gvk]++
+}
+
+// FallbackHits returns a snapshot of the fallback-hit counters, keyed by
+// the legacy GroupVersionKind that was served.
+func FallbackHits() map[schema.GroupVersionKind]int {
+	fallbackHitsMu.Lock()
+	defer fallbackHitsMu.Unlock()
+
+	snapshot := make(map[schema.GroupVersionKind]int, len(fallbackHits))
+	for gvk, count := range fallbackHits {
+		snapshot[gvk] = count
+	}
+	return snapshot
+}
+
+// GetFunc fetches an object of a single, specific API version.
+type GetFunc func(name string) (runtime.Object, error)
+
+// Getter fetches a resource from its current version, falling back to a
+// single legacy version (Legacy) when the current version reports the
+// object missing. No further versions are tried after Legacy.
+type Getter struct {
+	// GVK identifies the legacy version served by Legacy, used as the key
+	// in FallbackHits when that version is the one that answers the Get.
+	GVK schema.GroupVersionKind
+	// Current fetches the object from the current (v1) API.
+	Current GetFunc
+	// Legacy fetches the object from an older API version.
+	Legacy GetFunc
+	// Convert upgrades the object Legacy returned to its v1 equivalent.
+	// Defaults to the func registered for GVK via RegisterConversion if
+	// left nil.
+	Convert ConversionFunc
+}
+
+// Get fetches name via Current, falling back to Legacy+Convert when
+// Current reports the object NotFound or the v1 kind isn't registered
+// (NoKindMatchError, e.g. the CRD's storage version hasn't caught up yet).
+func (g *Getter) Get(name string) (runtime.Object, error) {
+	obj, err := g.Current(name)
+	if err == nil {
+		return obj, nil
+	}
+
+	if !k8serrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+		return nil, err
+	}
+
+	legacyObj, legacyErr := g.Legacy(name)
+	if legacyErr != nil {
+		// the current-version error is the one the caller asked about;
+		// surface it rather than the legacy lookup's.
+		return nil, err
+	}
+
+	convert := g.Convert
+	if convert == nil {
+		convert = conversions[g.GVK]
+	}
+	if convert == nil {
+		return nil, err
+	}
+
+	upgraded, convErr := convert(legacyObj)
+	if convErr != nil {
+		return nil, convErr
+	}
+
+	recordFallbackHit(g.GVK)
+
+	return upgraded, nil
+}
+
+// RetryGet runs Get with the default client-go backoff, retrying while Get
+// returns an InternalError; any other error is returned at once.
+func (g *Getter) RetryGet(name string) (obj runtime.Object, err error) {
+	retryErr := retry.OnError(retry.DefaultBackoff, k8serrors.IsInternalError, func() error {
+		obj, err = g.Get(name)
+		return err
+	})
+	if retryErr != nil {
+		return nil, retryErr
+	}
+	return obj, nil
+}
@@ -0,0 +1,87 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+type fakeObject struct{}
+
+func (f *fakeObject) GetObjectKind() schema.ObjectKind { return nil }
+func (f *fakeObject) DeepCopyObject() runtime.Object   { return &fakeObject{} }
+
+func TestGetterFallsBackOnNotFound(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "openebs.io", Version: "v1alpha1", Kind: "FakeFallback"}
+	RegisterConversion(gvk, func(obj runtime.Object) (runtime.Object, error) {
+		return obj, nil
+	})
+
+	g := &Getter{
+		GVK: gvk,
+		Current: func(name string) (runtime.Object, error) {
+			return nil, k8serrors.NewNotFound(schema.GroupResource{}, name)
+		},
+		Legacy: func(name string) (runtime.Object, error) {
+			return &fakeObject{}, nil
+		},
+	}
+
+	obj, err := g.Get("some-name")
+	if err != nil {
+		t.Fatalf("Get() error = %v, want nil", err)
+	}
+	if obj == nil {
+		t.Fatal("Get() returned nil object after a successful legacy fallback")
+	}
+}
+
+func TestRecordFallbackHitIsRaceFree(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "openebs.io", Version: "v1alpha1", Kind: "FakeConcurrent"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recordFallbackHit(gvk)
+		}()
+	}
+	wg.Wait()
+
+	hits := FallbackHits()
+	if hits[gvk] != 50 {
+		t.Errorf("FallbackHits()[gvk] = %d, want 50", hits[gvk])
+	}
+}
+
+func TestErrUnexpectedType(t *testing.T) {
+	gvk := schema.GroupVersionKind{Group: "openebs.io", Version: "v1alpha1", Kind: "FakeTypeMismatch"}
+
+	err := errUnexpectedType(gvk, &fakeObject{})
+	if err == nil {
+		t.Fatal("errUnexpectedType() returned nil, want an error describing the mismatch")
+	}
+	want := fmt.Sprintf("resource: expected %s, got %T", gvk, &fakeObject{})
+	if err.Error() != want {
+		t.Errorf("errUnexpectedType() = %q, want %q", err.Error(), want)
+	}
+}
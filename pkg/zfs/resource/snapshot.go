@@ -0,0 +1,69 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	v1 "github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1"
+	"github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1alpha1"
+	"github.com/openebs/zfs-localpv/pkg/builder/snapbuilder"
+	"github.com/openebs/zfs-localpv/pkg/builder/snapbuilder/v1alpha1builder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// snapshotGVK identifies the legacy v1alpha1 ZFSSnapshot kind this file's
+// conversion upgrades to v1.
+var snapshotGVK = schema.GroupVersionKind{
+	Group:   "openebs.io",
+	Version: "v1alpha1",
+	Kind:    "ZFSSnapshot",
+}
+
+func init() {
+	RegisterConversion(snapshotGVK, convertSnapshotV1alpha1ToV1)
+}
+
+// convertSnapshotV1alpha1ToV1 upgrades a v1alpha1.ZFSSnapshot to its v1
+// equivalent. Only ObjectMeta and Status.State are copied because they are
+// the only ZFSSnapshot fields this codebase reads today (see
+// GetZFSSnapshotStatus); extend this once a Spec field is needed by a
+// caller.
+func convertSnapshotV1alpha1ToV1(obj runtime.Object) (runtime.Object, error) {
+	legacy, ok := obj.(*v1alpha1.ZFSSnapshot)
+	if !ok {
+		return nil, errUnexpectedType(snapshotGVK, obj)
+	}
+
+	converted := &v1.ZFSSnapshot{ObjectMeta: legacy.ObjectMeta}
+	converted.Status.State = legacy.Status.State
+
+	return converted, nil
+}
+
+// NewSnapshotGetter returns a Getter that fetches a ZFSSnapshot from the
+// current v1 API, falling back to v1alpha1 and converting up when v1
+// reports the object missing.
+func NewSnapshotGetter(namespace string) *Getter {
+	return &Getter{
+		GVK: snapshotGVK,
+		Current: func(name string) (runtime.Object, error) {
+			return snapbuilder.NewKubeclient().WithNamespace(namespace).Get(name, metav1.GetOptions{})
+		},
+		Legacy: func(name string) (runtime.Object, error) {
+			return v1alpha1builder.NewKubeclient().WithNamespace(namespace).Get(name, metav1.GetOptions{})
+		},
+	}
+}
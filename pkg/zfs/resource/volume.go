@@ -0,0 +1,102 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	v1 "github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1"
+	"github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1alpha1"
+	"github.com/openebs/zfs-localpv/pkg/builder/volbuilder"
+	"github.com/openebs/zfs-localpv/pkg/builder/volbuilder/v1alpha1builder"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// volumeGVK identifies the legacy v1alpha1 ZFSVolume kind this file's
+// conversion upgrades to v1.
+var volumeGVK = schema.GroupVersionKind{
+	Group:   "openebs.io",
+	Version: "v1alpha1",
+	Kind:    "ZFSVolume",
+}
+
+func init() {
+	RegisterConversion(volumeGVK, convertVolumeV1alpha1ToV1)
+}
+
+// convertVolumeV1alpha1ToV1 upgrades a v1alpha1.ZFSVolume to its v1
+// equivalent. The two versions share every field used by this codebase, so
+// the conversion is a straight field copy.
+func convertVolumeV1alpha1ToV1(obj runtime.Object) (runtime.Object, error) {
+	legacy, ok := obj.(*v1alpha1.ZFSVolume)
+	if !ok {
+		return nil, errUnexpectedType(volumeGVK, obj)
+	}
+
+	return &v1.ZFSVolume{
+		ObjectMeta: legacy.ObjectMeta,
+		Spec: v1.VolumeInfo{
+			OwnerNodeID: legacy.Spec.OwnerNodeID,
+			PoolName:    legacy.Spec.PoolName,
+			Capacity:    legacy.Spec.Capacity,
+			FsType:      legacy.Spec.FsType,
+			VolumeType:  legacy.Spec.VolumeType,
+		},
+		Status: v1.VolStatus{
+			State: legacy.Status.State,
+		},
+	}, nil
+}
+
+// NewVolumeGetter returns a Getter that fetches a ZFSVolume from the
+// current v1 API, falling back to v1alpha1 and converting up when v1
+// reports the object missing.
+func NewVolumeGetter(namespace string) *Getter {
+	return &Getter{
+		GVK: volumeGVK,
+		Current: func(name string) (runtime.Object, error) {
+			return volbuilder.NewKubeclient().WithNamespace(namespace).Get(name, metav1.GetOptions{})
+		},
+		Legacy: func(name string) (runtime.Object, error) {
+			return v1alpha1builder.NewKubeclient().WithNamespace(namespace).Get(name, metav1.GetOptions{})
+		},
+	}
+}
+
+// ListVolumesFallback lists ZFSVolume CRs from the legacy v1alpha1 API and
+// converts each to v1. Callers such as GetVolList use this when their v1
+// List call reports meta.IsNoMatchError -- the CRD's storage version
+// hasn't caught up to v1 yet.
+func ListVolumesFallback(namespace string, listOptions metav1.ListOptions) (*v1.ZFSVolumeList, error) {
+	legacyList, err := v1alpha1builder.NewKubeclient().WithNamespace(namespace).List(listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := &v1.ZFSVolumeList{}
+	for i := range legacyList.Items {
+		obj, err := convertVolumeV1alpha1ToV1(&legacyList.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		converted.Items = append(converted.Items, *obj.(*v1.ZFSVolume))
+	}
+
+	if len(converted.Items) > 0 {
+		recordFallbackHit(volumeGVK)
+	}
+
+	return converted, nil
+}
@@ -0,0 +1,70 @@
+// Copyright © 2019 The OpenEBS Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package zfs
+
+import (
+	"testing"
+
+	apis "github.com/openebs/zfs-localpv/pkg/apis/openebs.io/zfs/v1"
+)
+
+func TestRestoreDatasetReady(t *testing.T) {
+	tests := map[string]struct {
+		rstr  *apis.ZFSRestore
+		ready bool
+	}{
+		"regular restore is always ready": {
+			rstr:  &apis.ZFSRestore{},
+			ready: true,
+		},
+		"encrypted restore waiting on zfs recv": {
+			rstr: func() *apis.ZFSRestore {
+				r := &apis.ZFSRestore{}
+				r.Spec.Mode = ZFSRestoreModeEncrypted
+				r.Status.State = ZFSStatusPending
+				return r
+			}(),
+			ready: false,
+		},
+		"encrypted restore with dataset ready": {
+			rstr: func() *apis.ZFSRestore {
+				r := &apis.ZFSRestore{}
+				r.Spec.Mode = ZFSRestoreModeEncrypted
+				r.Status.State = ZFSRestoreStatusDatasetReady
+				return r
+			}(),
+			ready: true,
+		},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := restoreDatasetReady(tt.rstr); got != tt.ready {
+				t.Errorf("restoreDatasetReady() = %v, want %v", got, tt.ready)
+			}
+		})
+	}
+}
+
+func TestProvisionVolumeForRestoreBlocksUntilDatasetReady(t *testing.T) {
+	rstr := &apis.ZFSRestore{}
+	rstr.Spec.Mode = ZFSRestoreModeEncrypted
+	rstr.Status.State = ZFSStatusPending
+
+	err := ProvisionVolumeForRestore(rstr, &apis.ZFSVolume{})
+	if err == nil {
+		t.Fatal("expected ProvisionVolumeForRestore to refuse binding before the dataset is ready")
+	}
+}
@@ -15,6 +15,7 @@
 package zfs
 
 import (
+	"fmt"
 	"os"
 	"strconv"
 
@@ -23,6 +24,8 @@ import (
 	"github.com/openebs/zfs-localpv/pkg/builder/restorebuilder"
 	"github.com/openebs/zfs-localpv/pkg/builder/snapbuilder"
 	"github.com/openebs/zfs-localpv/pkg/builder/volbuilder"
+	"github.com/openebs/zfs-localpv/pkg/zfs/resource"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
@@ -51,6 +54,21 @@ const (
 	ZFSStatusFailed string = "Failed"
 	// ZFSStatusReady shows object has been processed
 	ZFSStatusReady string = "Ready"
+
+	// ZFSRestoreModeEncrypted marks a ZFSRestore whose target pool has
+	// native ZFS encryption enabled. Encrypted pools reject a pre-created
+	// empty dataset colliding with an incoming `zfs recv` stream, so for
+	// this mode the node agent receives the stream first and the
+	// controller binds the ZFSVolume CR only once the dataset exists.
+	ZFSRestoreModeEncrypted string = "encrypted"
+	// ZFSRestoreStatusDatasetReady shows the node agent has finished
+	// `zfs recv` into the target pool and the dataset is present on disk,
+	// waiting for the controller to bind a ZFSVolume CR to it.
+	ZFSRestoreStatusDatasetReady string = "DatasetReady"
+
+	// ZFSRestoredVolKey labels a ZFSVolume CR that was bound to a dataset
+	// already materialized on the node, instead of being created fresh.
+	ZFSRestoredVolKey string = "openebs.io/restored-dataset"
 )
 
 var (
@@ -92,6 +110,28 @@ func ProvisionVolume(
 	return err
 }
 
+// ProvisionRestoredVolume creates a ZFSVolume(zv) CR for a dataset that has
+// already been materialized on the node, e.g. by a `zfs recv` into an
+// encrypted pool (see ZFSRestoreModeEncrypted). Unlike ProvisionVolume, the
+// node agent must not attempt a `zfs create`/`zfs clone` for this volume --
+// the dataset already exists and only needs to be bound.
+func ProvisionRestoredVolume(
+	vol *apis.ZFSVolume,
+) error {
+
+	if vol.Labels == nil {
+		vol.Labels = map[string]string{}
+	}
+	vol.Labels[ZFSRestoredVolKey] = "true"
+
+	_, err := volbuilder.NewKubeclient().WithNamespace(OpenEBSNamespace).Create(vol)
+	if err == nil {
+		klog.Infof("provisioned volume %s from restored dataset", vol.Name)
+	}
+
+	return err
+}
+
 // ResizeVolume resizes the zfs volume
 func ResizeVolume(vol *apis.ZFSVolume, newSize int64) error {
 
@@ -125,11 +165,15 @@ func DeleteSnapshot(snapname string) (err error) {
 	return
 }
 
-// GetVolume the corresponding ZFSVolume CR
+// GetVolume the corresponding ZFSVolume CR. It falls back to the
+// v1alpha1 API, converting the result up to v1, when the v1 kind isn't
+// served yet -- see pkg/zfs/resource for why that can happen mid upgrade.
 func GetVolume(volumeID string) (*apis.ZFSVolume, error) {
-	return volbuilder.NewKubeclient().
-		WithNamespace(OpenEBSNamespace).
-		Get(volumeID, metav1.GetOptions{})
+	obj, err := resource.NewVolumeGetter(OpenEBSNamespace).Get(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*apis.ZFSVolume), nil
 }
 
 // DeleteVolume deletes the corresponding ZFSVol CR
@@ -142,23 +186,49 @@ func DeleteVolume(volumeID string) (err error) {
 	return
 }
 
-// GetVolList fetches the current Published Volume list
+// GetVolList fetches the current Published Volume list. It falls back to
+// the v1alpha1 API, converting each item up to v1, when the v1 kind isn't
+// served yet.
 func GetVolList(volumeID string) (*apis.ZFSVolumeList, error) {
 	listOptions := v1.ListOptions{
 		LabelSelector: ZFSNodeKey + "=" + NodeID,
 	}
 
-	return volbuilder.NewKubeclient().
+	volList, err := volbuilder.NewKubeclient().
 		WithNamespace(OpenEBSNamespace).List(listOptions)
+	if err == nil || !meta.IsNoMatchError(err) {
+		return volList, err
+	}
 
+	return resource.ListVolumesFallback(OpenEBSNamespace, listOptions)
 }
 
-// GetZFSVolume fetches the given ZFSVolume
+// ListVolumes returns every ZFSVolume CR in OpenEBSNamespace. Unlike
+// GetVolList, which is scoped to the volumes published on this node, this
+// is meant for cluster-wide callers such as the lint subsystem.
+func ListVolumes() (*apis.ZFSVolumeList, error) {
+	return volbuilder.NewKubeclient().WithNamespace(OpenEBSNamespace).List(v1.ListOptions{})
+}
+
+// ListSnapshots returns every ZFSSnapshot CR in OpenEBSNamespace.
+func ListSnapshots() (*apis.ZFSSnapshotList, error) {
+	return snapbuilder.NewKubeclient().WithNamespace(OpenEBSNamespace).List(v1.ListOptions{})
+}
+
+// ListBackups returns every ZFSBackup CR in OpenEBSNamespace.
+func ListBackups() (*apis.ZFSBackupList, error) {
+	return bkpbuilder.NewKubeclient().WithNamespace(OpenEBSNamespace).List(v1.ListOptions{})
+}
+
+// ListRestores returns every ZFSRestore CR in OpenEBSNamespace.
+func ListRestores() (*apis.ZFSRestoreList, error) {
+	return restorebuilder.NewKubeclient().WithNamespace(OpenEBSNamespace).List(v1.ListOptions{})
+}
+
+// GetZFSVolume fetches the given ZFSVolume, falling back to v1alpha1 the
+// same way GetVolume does.
 func GetZFSVolume(volumeID string) (*apis.ZFSVolume, error) {
-	getOptions := metav1.GetOptions{}
-	vol, err := volbuilder.NewKubeclient().
-		WithNamespace(OpenEBSNamespace).Get(volumeID, getOptions)
-	return vol, err
+	return GetVolume(volumeID)
 }
 
 // GetZFSVolumeState returns ZFSVolume OwnerNode and State for
@@ -206,12 +276,14 @@ func RemoveZvolFinalizer(vol *apis.ZFSVolume) error {
 	return err
 }
 
-// GetZFSSnapshot fetches the given ZFSSnapshot
+// GetZFSSnapshot fetches the given ZFSSnapshot, falling back to the
+// v1alpha1 API and converting up to v1 when the v1 kind isn't served yet.
 func GetZFSSnapshot(snapID string) (*apis.ZFSSnapshot, error) {
-	getOptions := metav1.GetOptions{}
-	snap, err := snapbuilder.NewKubeclient().
-		WithNamespace(OpenEBSNamespace).Get(snapID, getOptions)
-	return snap, err
+	obj, err := resource.NewSnapshotGetter(OpenEBSNamespace).Get(snapID)
+	if err != nil {
+		return nil, err
+	}
+	return obj.(*apis.ZFSSnapshot), nil
 }
 
 // GetZFSSnapshotStatus returns ZFSSnapshot status
@@ -286,6 +358,46 @@ func UpdateBkpInfo(bkp *apis.ZFSBackup, status apis.ZFSBackupStatus) error {
 	return err
 }
 
+// IsEncryptedRestore tells whether the given ZFSRestore targets an
+// encrypted pool and therefore needs the deferred-ZFSVolume-creation flow:
+// `zfs recv` on the node agent first, ProvisionRestoredVolume second.
+func IsEncryptedRestore(rstr *apis.ZFSRestore) bool {
+	return rstr.Spec.Mode == ZFSRestoreModeEncrypted
+}
+
+// restoreDatasetReady reports whether it is safe to bind a ZFSVolume CR for
+// rstr yet. A regular restore is always ready -- the controller creates the
+// CR up front as before. An encrypted-pool restore is ready only once the
+// node agent has finished `zfs recv` and flipped the CR to
+// ZFSRestoreStatusDatasetReady.
+func restoreDatasetReady(rstr *apis.ZFSRestore) bool {
+	return !IsEncryptedRestore(rstr) || rstr.Status.State == ZFSRestoreStatusDatasetReady
+}
+
+// ProvisionVolumeForRestore is the restore-path entry point the CSI
+// controller calls in place of a bare ProvisionVolume. For a regular
+// restore it behaves exactly like ProvisionVolume: the ZFSVolume CR is
+// created up front and the node agent creates the dataset into it. For an
+// encrypted-pool restore (IsEncryptedRestore) it instead defers: the
+// controller must keep polling rstr and only call this once the node
+// agent has signalled ZFSRestoreStatusDatasetReady, at which point the
+// dataset already exists and ProvisionRestoredVolume binds the ZFSVolume
+// CR to it without a `zfs create`.
+func ProvisionVolumeForRestore(rstr *apis.ZFSRestore, vol *apis.ZFSVolume) error {
+	if !restoreDatasetReady(rstr) {
+		return fmt.Errorf(
+			"restore %s: waiting for node agent to finish zfs recv (status %q)",
+			rstr.Name, rstr.Status.State,
+		)
+	}
+
+	if IsEncryptedRestore(rstr) {
+		return ProvisionRestoredVolume(vol)
+	}
+
+	return ProvisionVolume(vol)
+}
+
 // UpdateRestoreInfo updates the rstr info with the status
 func UpdateRestoreInfo(rstr *apis.ZFSRestore, status apis.ZFSRestoreStatus) error {
 	newRstr, err := restorebuilder.BuildFrom(rstr).Build()